@@ -0,0 +1,104 @@
+package olconv
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// AnchorStyle selects how heading fragments are written in Markdown links
+type AnchorStyle int
+
+const (
+	// ObsidianAnchor keeps the heading text and encodes spaces, e.g. #Setup%20steps
+	ObsidianAnchor AnchorStyle = iota
+	// GitHubAnchor writes the heading as a GitHub-style slug, e.g. #setup-steps
+	GitHubAnchor
+)
+
+// ParseAnchorStyle converts a flag value into an AnchorStyle
+func ParseAnchorStyle(s string) (AnchorStyle, bool) {
+	switch s {
+	case "obsidian":
+		return ObsidianAnchor, true
+	case "github":
+		return GitHubAnchor, true
+	default:
+		return ObsidianAnchor, false
+	}
+}
+
+// formatAnchor renders a heading as a Markdown link fragment.
+// Block references are written verbatim in every style.
+func formatAnchor(heading string, style AnchorStyle) string {
+	if isBlockRef(heading) {
+		return heading
+	}
+	switch style {
+	case GitHubAnchor:
+		return slugify(heading)
+	default:
+		return strings.ReplaceAll(heading, " ", "%20")
+	}
+}
+
+// parseAnchor decodes a Markdown link fragment back into heading text
+func parseAnchor(fragment string) string {
+	if heading, err := url.PathUnescape(fragment); err == nil {
+		return heading
+	}
+	return fragment
+}
+
+// isBlockRef reports whether a fragment refers to a block (^blockid) rather than a heading
+func isBlockRef(fragment string) bool {
+	return strings.HasPrefix(fragment, "^")
+}
+
+// slugify generates a GitHub-style anchor for a heading
+func slugify(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsNumber(r), r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// readHeadings returns the headings of the note at path
+func readHeadings(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return documentHeadings(splitDocument(string(content)).lines)
+}
+
+// documentHeadings returns the text of every ATX heading in the lines of a note, skipping the frontmatter, code and HTML blocks
+func documentHeadings(lines []string) []string {
+	body := 0
+	if end := frontmatterEnd(lines); end != -1 {
+		body = end + 1
+	}
+
+	headings := make([]string, 0)
+	blocks := blockTokenizer{}
+	for _, line := range lines[body:] {
+		if blocks.next(line) != textBlock {
+			continue
+		}
+
+		text := strings.TrimLeft(line, "#")
+		level := len(line) - len(text)
+		if level == 0 || level > 6 || !strings.HasPrefix(text, " ") {
+			continue
+		}
+		headings = append(headings, strings.TrimSpace(text))
+	}
+	return headings
+}
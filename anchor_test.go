@@ -0,0 +1,65 @@
+package olconv
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name    string
+		heading string
+		want    string
+	}{
+		{
+			name:    "spaces",
+			heading: "Setup steps",
+			want:    "setup-steps",
+		},
+		{
+			name:    "punctuation",
+			heading: "What's new? (v2.0)",
+			want:    "whats-new-v20",
+		},
+		{
+			name:    "japanese",
+			heading: "使い方 ガイド",
+			want:    "使い方-ガイド",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, slugify(tt.heading))
+		})
+	}
+}
+
+func TestConverter_headingFromAnchor(t *testing.T) {
+	tempDir := t.TempDir()
+	note := filepath.Join(tempDir, "note.md")
+	err := os.WriteFile(note, []byte("# Note\n\n## Setup steps\n\n```\n# not a heading\n```\n"), 0644)
+	require.NoError(t, err)
+
+	c := NewConverter(tempDir, nil)
+
+	assert.Equal(t, "Setup steps", c.headingFromAnchor(note, "setup-steps"))
+	assert.Equal(t, "Setup steps", c.headingFromAnchor(note, "Setup%20steps"))
+	assert.Equal(t, "^abc123", c.headingFromAnchor(note, "^abc123"))
+	assert.Equal(t, "not-a-heading", c.headingFromAnchor(note, "not-a-heading"))
+	assert.Equal(t, "Unknown heading", c.headingFromAnchor(filepath.Join(tempDir, "missing.md"), "Unknown%20heading"))
+}
+
+func TestConvert_SameNoteAnchorsFromContent(t *testing.T) {
+	// the note is not on disk, like a note read from stdin
+	c := NewConverter(t.TempDir(), nil)
+	w := &strings.Builder{}
+	err := c.Convert(strings.NewReader("# Setup steps\n\nSee [here](#setup-steps) and [[#Setup steps]]\n"), w, filepath.Join(t.TempDir(), "unsaved.md"), ToWikilink)
+	require.NoError(t, err)
+	assert.Equal(t, "# Setup steps\n\nSee [[#Setup steps|here]] and [[#Setup steps]]\n", w.String())
+}
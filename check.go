@@ -0,0 +1,127 @@
+package olconv
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ProblemKind classifies the problems reported by CheckVault
+type ProblemKind string
+
+const (
+	// BrokenLink is a link whose target does not exist in the vault
+	BrokenLink ProblemKind = "broken"
+	// AmbiguousLink is a wikilink whose target matches several files in the vault
+	AmbiguousLink ProblemKind = "ambiguous"
+)
+
+// Problem is a link found by CheckVault that does not point to exactly one file of the vault
+type Problem struct {
+	// File is the path of the note from the vault root
+	File string `json:"file"`
+	// Line and Column are the 1-based position of the link, Column counting bytes
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Link is the link as written in the note
+	Link    string      `json:"link"`
+	Kind    ProblemKind `json:"kind"`
+	Message string      `json:"message"`
+}
+
+// String formats the problem as file:line:col: message
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+}
+
+// CheckVault reports every link of the vault whose target cannot be found and every ambiguous wikilink.
+// Nothing is written to the vault. Notes listed in .olconvignore are not checked, but links to them are valid.
+func CheckVault(basepath string) ([]Problem, error) {
+	files, c, err := newVaultConverter(basepath, Options{IndexExcluded: true})
+	if err != nil {
+		return nil, err
+	}
+
+	problems := make([]Problem, 0)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		found, err := c.check(f, file)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, found...)
+	}
+	return problems, nil
+}
+
+// check reports the problems of the links of the note read from r
+func (c *Converter) check(r io.Reader, path string) ([]Problem, error) {
+	links, err := ExtractLinks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c = c.forNote(path)
+
+	problems := make([]Problem, 0)
+	for _, link := range links {
+		kind, message, ok := c.checkLink(link)
+		if !ok {
+			continue
+		}
+		problems = append(problems, Problem{
+			File:    c.vaultPath(path),
+			Line:    link.Line,
+			Column:  link.Column,
+			Link:    link.Raw,
+			Kind:    kind,
+			Message: message,
+		})
+	}
+	return problems, nil
+}
+
+// urlSchemePattern matches destinations with a URL scheme, such as https: or mailto:
+var urlSchemePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+
+// checkLink returns the problem of a link of the note being checked, if it has one
+func (c *Converter) checkLink(link Link) (ProblemKind, string, bool) {
+	if link.Target == "" {
+		return "", "", false
+	}
+
+	if link.Kind == MarkdownLinkKind {
+		if urlSchemePattern.MatchString(link.Target) {
+			return "", "", false
+		}
+		if _, _, ok := c.findDestination(link.Target); ok {
+			return "", "", false
+		}
+		if _, ok := decodeDestination(link.Target); !ok {
+			return BrokenLink, fmt.Sprintf("link target %q has an invalid percent-encoding", link.Target), true
+		}
+		if _, ok := c.resolveDestination(link.Target); !ok {
+			return BrokenLink, fmt.Sprintf("link target %q is outside the vault", link.Target), true
+		}
+		return BrokenLink, fmt.Sprintf("link target %q not found", link.Target), true
+	}
+
+	matches := c.matchWikilink(link.Target)
+	switch {
+	case len(matches) == 0:
+		return BrokenLink, fmt.Sprintf("wikilink target %q not found", link.Target), true
+	case len(matches) > 1:
+		paths := make([]string, 0, len(matches))
+		for _, file := range matches {
+			paths = append(paths, c.vaultPath(file))
+		}
+		return AmbiguousLink, fmt.Sprintf("wikilink target %q is ambiguous, it matches %s", link.Target, strings.Join(paths, ", ")), true
+	}
+	return "", "", false
+}
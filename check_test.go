@@ -0,0 +1,48 @@
+package olconv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_check(t *testing.T) {
+	c := NewConverter("vault", map[string][]string{
+		"note":        {"vault/note.md"},
+		"a+b":         {"vault/a+b.md"},
+		"samename":    {"vault/sub1/samename.md", "vault/sub2/samename.md"},
+		"diagram.png": {"vault/attachments/diagram.png"},
+	})
+
+	note := "---\n" +
+		"related: [[missing in frontmatter]]\n" +
+		"---\n" +
+		"[ok](note.md) [[note#Heading]] ![[diagram.png|300]] [site](https://example.com) [top](#Top)\n" +
+		"[broken](missing.md) and [[Missing]]\n" +
+		"```\n" +
+		"[[in code block]]\n" +
+		"```\n" +
+		"[[samename]] [[sub1/samename]] `[[in code span]]`\n" +
+		"![](../outside.png) ![[lost.png]]\n" +
+		"[plus](a+b.md) [percent](100%.md)\n"
+
+	problems, err := c.check(strings.NewReader(note), "vault/index.md")
+	require.NoError(t, err)
+
+	want := []Problem{
+		{File: "index.md", Line: 5, Column: 1, Link: "[broken](missing.md)", Kind: BrokenLink, Message: `link target "missing.md" not found`},
+		{File: "index.md", Line: 5, Column: 26, Link: "[[Missing]]", Kind: BrokenLink, Message: `wikilink target "Missing" not found`},
+		{File: "index.md", Line: 9, Column: 1, Link: "[[samename]]", Kind: AmbiguousLink, Message: `wikilink target "samename" is ambiguous, it matches sub1/samename.md, sub2/samename.md`},
+		{File: "index.md", Line: 10, Column: 1, Link: "![](../outside.png)", Kind: BrokenLink, Message: `link target "../outside.png" is outside the vault`},
+		{File: "index.md", Line: 10, Column: 21, Link: "![[lost.png]]", Kind: BrokenLink, Message: `wikilink target "lost.png" not found`},
+		{File: "index.md", Line: 11, Column: 16, Link: "[percent](100%.md)", Kind: BrokenLink, Message: `link target "100%.md" has an invalid percent-encoding`},
+	}
+	assert.Equal(t, want, problems)
+}
+
+func TestProblem_String(t *testing.T) {
+	p := Problem{File: "sub/note.md", Line: 3, Column: 7, Message: `wikilink target "x" not found`}
+	assert.Equal(t, `sub/note.md:3:7: wikilink target "x" not found`, p.String())
+}
@@ -1,39 +1,498 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
 
 	"github.com/ikorihn/olconv"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "undo":
+			undo(os.Args[2:])
+			return
+		case "check":
+			check(os.Args[2:])
+			return
+		case "mv":
+			mv(os.Args[2:])
+			return
+		case "graph":
+			graph(os.Args[2:])
+			return
+		case "backlinks":
+			backlinks(os.Args[2:])
+			return
+		case "orphans":
+			orphans(os.Args[2:])
+			return
+		case "deadends":
+			deadends(os.Args[2:])
+			return
+		}
+	}
+
 	var basepath string
 	var toWiki bool
 	var toMarkdown bool
+	var anchorStyle string
+	var encoding string
+	var dryRun bool
+	var preserveMtime bool
+	var backup bool
+	var frontmatter string
+	var pathStyle string
+	var stdinFilepath string
+	var include globList
+	var exclude globList
+	var gitignore bool
+	var indexExcluded bool
+	var jobs int
+	var continueOnError bool
 
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: olconv [options] [- | <file>...]\n\nConvert the links of every note under -basepath, of the given notes, or of stdin to stdout with -.\n\n")
+		flag.PrintDefaults()
+	}
 	flag.StringVar(&basepath, "basepath", ".", "specify target directory")
 	flag.BoolVar(&toWiki, "to-wiki", false, "convert Markdown links to Wikilinks")
 	flag.BoolVar(&toMarkdown, "to-markdown", false, "convert Wikilinks to Markdown links")
+	flag.StringVar(&anchorStyle, "anchor-style", "obsidian", "heading anchor style in Markdown links (obsidian, github)")
+	flag.StringVar(&encoding, "encoding", "percent", "how spaces in Markdown link paths are written (percent, angle)")
+	flag.BoolVar(&dryRun, "dry-run", false, "print a diff of the changes without writing files, and exit with status 1 if there are any")
+	flag.BoolVar(&preserveMtime, "preserve-mtime", false, "keep the modification time of rewritten files")
+	flag.BoolVar(&backup, "backup", false, "record the original content of rewritten files in "+olconv.JournalDir+" so that the run can be undone with 'olconv undo'")
+	flag.StringVar(&frontmatter, "frontmatter", "skip", "handling of links in YAML frontmatter (skip, convert)")
+	flag.StringVar(&pathStyle, "path-style", "", "path of converted links (shortest, absolute, relative), defaults to newLinkFormat of .obsidian/app.json")
+	flag.StringVar(&stdinFilepath, "stdin-filepath", "", "path of the note read from stdin, used to find the vault and resolve relative links")
+	flag.Var(&include, "include", "convert only the notes matching this glob, such as 'notes/**' (repeatable)")
+	flag.Var(&exclude, "exclude", "leave the files matching this glob unchanged, in addition to "+olconv.IgnoreFile+" (repeatable)")
+	flag.BoolVar(&gitignore, "gitignore", false, "also leave the files listed in .gitignore unchanged")
+	flag.BoolVar(&indexExcluded, "index-excluded", false, "resolve links to excluded files")
+	flag.IntVar(&jobs, "jobs", 0, "number of notes converted in parallel, defaults to the number of CPUs")
+	flag.BoolVar(&continueOnError, "continue-on-error", false, "convert the other notes when a note cannot be converted, and report every error at the end")
 	flag.Parse()
 
+	args := flag.Args()
+	stdin := len(args) == 1 && args[0] == "-"
+	for _, arg := range args {
+		if arg == "-" && !stdin {
+			usageError("- cannot be combined with files")
+		}
+	}
+
+	// ファイルや標準入力を変換する場合は、それを含む Vault を探してリンクを解決する
+	basepathSet := false
+	flag.Visit(func(f *flag.Flag) {
+		basepathSet = basepathSet || f.Name == "basepath"
+	})
+	if !basepathSet && len(args) > 0 {
+		start := args[0]
+		if stdin {
+			start = "."
+			if stdinFilepath != "" {
+				start = stdinFilepath
+			}
+		}
+		if root, ok := olconv.FindVaultRoot(start); ok {
+			basepath = root
+		}
+	}
+
+	// どちらも指定されていない場合は Obsidian の設定に従う
+	if !toWiki && !toMarkdown {
+		config, err := olconv.ReadObsidianConfig(basepath)
+		if err != nil {
+			fail(err)
+		}
+		if config != nil {
+			toWiki = config.Direction() == olconv.ToWikilink
+			toMarkdown = !toWiki
+		}
+	}
+
 	// どちらも指定されていない、または両方指定されている場合
 	if (!toWiki && !toMarkdown) || (toWiki && toMarkdown) {
-		fmt.Fprintf(os.Stderr, "Error: Please specify either --to-wiki or --to-markdown\n\n")
-		flag.Usage()
-		os.Exit(1)
+		usageError("Please specify either --to-wiki or --to-markdown, or run in a vault with .obsidian/app.json")
+	}
+
+	style, ok := olconv.ParseAnchorStyle(anchorStyle)
+	if !ok {
+		usageError("Unknown anchor style %q", anchorStyle)
+	}
+
+	pathStyleValue, ok := olconv.ParsePathStyle(pathStyle)
+	if !ok {
+		usageError("Unknown path style %q", pathStyle)
+	}
+
+	frontmatterMode, ok := olconv.ParseFrontmatterMode(frontmatter)
+	if !ok {
+		usageError("Unknown frontmatter mode %q", frontmatter)
+	}
+
+	encodingPolicy, ok := olconv.ParseEncodingPolicy(encoding)
+	if !ok {
+		usageError("Unknown encoding %q", encoding)
 	}
 
-	var err error
+	opts := olconv.Options{
+		Direction:       olconv.ToMarkdown,
+		PathStyle:       pathStyleValue,
+		AnchorStyle:     style,
+		Encoding:        encodingPolicy,
+		FrontmatterMode: frontmatterMode,
+		Include:         include,
+		Exclude:         exclude,
+		GitIgnore:       gitignore,
+		IndexExcluded:   indexExcluded,
+		Jobs:            jobs,
+		ContinueOnError: continueOnError,
+	}
 	if toWiki {
-		err = olconv.LinkToWikilink(basepath)
-	} else {
-		err = olconv.WikilinkToLink(basepath)
+		opts.Direction = olconv.ToWikilink
+	}
+
+	if stdin {
+		if dryRun || backup {
+			usageError("-dry-run and -backup cannot be used with -")
+		}
+		if err := olconv.ConvertReader(basepath, stdinFilepath, os.Stdin, os.Stdout, opts); err != nil {
+			fail(err)
+		}
+		return
+	}
+	opts.Files = args
+
+	// Ctrl-C ではファイルの間で変換を止め、変換済みのファイルとバックアップを残す
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if dryRun {
+		opts.DryRun = true
+		opts.Diff = os.Stdout
+		summary, err := olconv.ConvertVault(ctx, basepath, opts)
+		if err != nil {
+			printErrors(err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "%d files, %d links would be changed\n", len(summary.Files), summary.Links)
+		if len(summary.Files) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if preserveMtime {
+		opts.Mtime = olconv.PreserveMtime
+	}
+
+	if backup {
+		journal, err := olconv.NewJournal(basepath)
+		if err != nil {
+			fail(err)
+		}
+		opts.Journal = journal
+	}
+
+	summary, err := olconv.ConvertVault(ctx, basepath, opts)
+
+	// 途中で失敗した場合も、それまでに書き換えたファイルは undo で戻せる
+	if journal := opts.Journal; journal != nil {
+		if closeErr := journal.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		} else if journal.Len() > 0 {
+			fmt.Fprintf(os.Stderr, "Backup saved as run %s\n", journal.Run())
+		}
 	}
 
 	if err != nil {
+		printErrors(err)
+		printSummary(summary)
+		os.Exit(1)
+	}
+}
+
+// printSummary は中断や失敗のあとに、どのファイルが変換済みかを表示する
+func printSummary(summary olconv.Summary) {
+	for _, file := range summary.Files {
+		fmt.Fprintf(os.Stderr, "Converted: %s\n", file)
+	}
+	for _, file := range summary.Failed {
+		fmt.Fprintf(os.Stderr, "Failed: %s\n", file)
+	}
+	fmt.Fprintf(os.Stderr, "%d files converted, %d failed\n", len(summary.Files), len(summary.Failed))
+}
+
+// usageError はエラーと使い方を表示して終了する
+func usageError(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n\n", args...)
+	flag.Usage()
+	os.Exit(1)
+}
+
+// printErrors は errors.Join でまとめられたエラーを 1 行ずつ表示する
+func printErrors(err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintf(os.Stderr, "Interrupted\n")
+			continue
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+// globList は繰り返し指定できる glob のフラグ
+type globList []string
+
+func (g *globList) String() string {
+	return strings.Join(*g, ",")
+}
+
+func (g *globList) Set(value string) error {
+	*g = append(*g, value)
+	return nil
+}
+
+// undo は -backup で記録した変換を元に戻す
+func undo(args []string) {
+	fs := flag.NewFlagSet("undo", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: olconv undo [-basepath <path>] [-list] [run]\n\nRestore the latest conversion run, or the given one.\n\n")
+		fs.PrintDefaults()
+	}
+
+	var basepath string
+	var list bool
+	fs.StringVar(&basepath, "basepath", ".", "specify target directory")
+	fs.BoolVar(&list, "list", false, "list the recorded runs")
+	fs.Parse(args)
+
+	if list {
+		runs, err := olconv.JournalRuns(basepath)
+		if err != nil {
+			fail(err)
+		}
+		for _, run := range runs {
+			fmt.Println(run)
+		}
+		return
+	}
+
+	if fs.NArg() > 1 {
+		fs.Usage()
 		os.Exit(1)
 	}
+
+	run, err := olconv.Undo(basepath, fs.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "Restored run %s\n", run)
+}
+
+// check は Vault を変更せずに壊れたリンクと曖昧なリンクを報告し、見つかった場合は終了コード 1 で終了する
+func check(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: olconv check [-basepath <path>] [-format text|json]\n\nReport links whose target cannot be found and ambiguous wikilinks.\n\n")
+		fs.PrintDefaults()
+	}
+
+	var basepath string
+	var format string
+	fs.StringVar(&basepath, "basepath", ".", "specify target directory")
+	fs.StringVar(&format, "format", "text", "output format (text, json)")
+	fs.Parse(args)
+
+	if fs.NArg() > 0 || (format != "text" && format != "json") {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	problems, err := olconv.CheckVault(basepath)
+	if err != nil {
+		fail(err)
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(problems); err != nil {
+			fail(err)
+		}
+	default:
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+	}
+
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+// mv はノートを移動し、そのノートへのリンクを書き換える
+func mv(args []string) {
+	fs := flag.NewFlagSet("mv", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: olconv mv [-basepath <path>] [-preserve-mtime] <source> <destination>\n\nMove a note, or a note into a directory, and update every link to it.\n\n")
+		fs.PrintDefaults()
+	}
+
+	var basepath string
+	var preserveMtime bool
+	fs.StringVar(&basepath, "basepath", ".", "specify target directory")
+	fs.BoolVar(&preserveMtime, "preserve-mtime", false, "keep the modification time of rewritten files")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	mtime := olconv.UpdateMtime
+	if preserveMtime {
+		mtime = olconv.PreserveMtime
+	}
+
+	if err := olconv.MoveNote(basepath, fs.Arg(0), fs.Arg(1), mtime); err != nil {
+		fail(err)
+	}
+}
+
+// graph は Vault のノート間のリンクを出力する
+func graph(args []string) {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: olconv graph [-basepath <path>] [-format json|dot|graphml]\n\nPrint the note-to-note link graph of the vault.\n\n")
+		fs.PrintDefaults()
+	}
+
+	var basepath string
+	var format string
+	fs.StringVar(&basepath, "basepath", ".", "specify target directory")
+	fs.StringVar(&format, "format", "json", "output format (json, dot, graphml)")
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	g, err := olconv.BuildGraph(basepath)
+	if err != nil {
+		fail(err)
+	}
+
+	switch format {
+	case "json":
+		err = g.WriteJSON(os.Stdout)
+	case "dot":
+		err = g.WriteDOT(os.Stdout)
+	case "graphml":
+		err = g.WriteGraphML(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Unknown format %q\n\n", format)
+		fs.Usage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+// backlinks はノートへのリンクを一覧する
+func backlinks(args []string) {
+	fs := flag.NewFlagSet("backlinks", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: olconv backlinks [-basepath <path>] <note>\n\nList every file and line linking to the note, given by its path from the vault root or by its name as in a wikilink.\n\n")
+		fs.PrintDefaults()
+	}
+
+	var basepath string
+	fs.StringVar(&basepath, "basepath", ".", "specify target directory")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// ノートは Vault のルートからのパスか、wikilink と同じ名前で指定する
+	g := buildGraph(basepath)
+	notes := g.FindNodes(fs.Arg(0))
+	switch len(notes) {
+	case 0:
+		fmt.Fprintf(os.Stderr, "Error: %s is not a note of the vault %s\n", fs.Arg(0), basepath)
+		os.Exit(1)
+	case 1:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %s is ambiguous, it matches %s\n", fs.Arg(0), strings.Join(notes, ", "))
+		os.Exit(1)
+	}
+
+	for _, e := range g.Backlinks(notes[0]) {
+		fmt.Printf("%s:%d: %s\n", e.Source, e.Line, e.Text)
+	}
+}
+
+// orphans はどのノートからもリンクされていないノートを一覧する
+func orphans(args []string) {
+	printNotes("orphans", "List the notes no other note links to.", args, (*olconv.Graph).Orphans)
+}
+
+// deadends は他のノートへリンクしていないノートを一覧する
+func deadends(args []string) {
+	printNotes("deadends", "List the notes that do not link to any other note.", args, (*olconv.Graph).Deadends)
+}
+
+// printNotes は Graph から選んだノートを 1 行ずつ出力する
+func printNotes(name, description string, args []string, notes func(*olconv.Graph) []string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: olconv %s [-basepath <path>]\n\n%s\n\n", name, description)
+		fs.PrintDefaults()
+	}
+
+	var basepath string
+	fs.StringVar(&basepath, "basepath", ".", "specify target directory")
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	for _, note := range notes(buildGraph(basepath)) {
+		fmt.Println(note)
+	}
+}
+
+// buildGraph は Vault のリンクグラフを作る
+func buildGraph(basepath string) *olconv.Graph {
+	g, err := olconv.BuildGraph(basepath)
+	if err != nil {
+		fail(err)
+	}
+	return g
+}
+
+// fail はエラーを表示して終了する
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
 }
@@ -1,9 +1,9 @@
 package olconv
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"path/filepath"
 	"strings"
 )
 
@@ -12,46 +12,164 @@ type LinkDirection int
 const (
 	ToWikilink LinkDirection = iota
 	ToMarkdown
+	// RewriteOnly leaves every link as it is unless the Rewrite hook of the Converter replaces it
+	RewriteOnly
 )
 
+// PathStyle selects how the path to a linked file is written
+type PathStyle int
+
+const (
+	// DefaultPath writes the shortest unique wikilinks and Markdown links relative to the note.
+	// Vault conversions use the newLinkFormat of the vault's Obsidian configuration instead when there is one.
+	DefaultPath PathStyle = iota
+	// ShortestPath writes only the filename when it is unique in the vault, like Obsidian's "shortest" link format
+	ShortestPath
+	// AbsolutePath always writes the path from the vault root
+	AbsolutePath
+	// RelativePath always writes the path relative to the note
+	RelativePath
+)
+
+// ParsePathStyle converts a flag value or an Obsidian newLinkFormat setting into a PathStyle
+func ParsePathStyle(s string) (PathStyle, bool) {
+	switch s {
+	case "", "default":
+		return DefaultPath, true
+	case "shortest":
+		return ShortestPath, true
+	case "absolute":
+		return AbsolutePath, true
+	case "relative":
+		return RelativePath, true
+	default:
+		return DefaultPath, false
+	}
+}
+
 type Converter struct {
-	inCodeBlock bool
-	filemap     map[string][]string
+	// AnchorStyle controls how heading fragments are written when converting to Markdown links
+	AnchorStyle AnchorStyle
+	// FrontmatterMode controls whether links in the YAML frontmatter are converted
+	FrontmatterMode FrontmatterMode
+	// PathStyle controls how the paths of converted links are written
+	PathStyle PathStyle
+	// Encoding controls how spaces in the paths of converted Markdown links are written
+	Encoding EncodingPolicy
+	// AttachmentFolder is where attachments that cannot be found are assumed to be, in Obsidian's attachmentFolderPath format
+	AttachmentFolder string
+	// Resolver finds the files links point to
+	Resolver Resolver
+	// Rewrite, when set, is called for every link outside the frontmatter before it is converted.
+	// Links it does not change are converted in the direction passed to Convert.
+	Rewrite RewriteFunc
+
+	// state of the note being converted, only set on the copies made by forNote
+	converted int
+	basepath  string
+	notePath  string
+	// headings of the note, taken from the content being converted
+	headings []string
+	// position of the line being converted, and the code spans it shares with its paragraph
+	line   int
+	offset int
+	carry  spanCarry
 }
 
-func NewConverter(filemap map[string][]string) *Converter {
+// NewConverter returns a Converter for the vault at basepath configured by opts.
+// Links are resolved with a FileMapResolver of filemap unless a Resolver is given.
+func NewConverter(basepath string, filemap map[string][]string, opts ...Option) *Converter {
+	o := Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	resolver := o.Resolver
+	if resolver == nil {
+		resolver = NewFileMapResolver(basepath, filemap)
+	}
 	return &Converter{
-		filemap: filemap,
+		AnchorStyle:      o.AnchorStyle,
+		FrontmatterMode:  o.FrontmatterMode,
+		PathStyle:        o.PathStyle,
+		Encoding:         o.Encoding,
+		AttachmentFolder: o.AttachmentFolder,
+		Resolver:         resolver,
+		Rewrite:          o.Rewrite,
+		basepath:         basepath,
 	}
 }
 
-func (c *Converter) Convert(r io.Reader, w io.Writer, newLineAtEnd bool, direction LinkDirection) error {
-	sc := bufio.NewScanner(r)
-	bw := bufio.NewWriter(w)
+// Convert rewrites the links of the note read from r into w.
+// path is the location of the note and is used to resolve relative link destinations.
+// Only the bytes of the rewritten links change: line endings, a byte order mark and the final newline are kept as they are.
+// A Converter can convert several notes concurrently, as long as its Resolver and Rewrite hook can be called concurrently.
+func (c *Converter) Convert(r io.Reader, w io.Writer, path string, direction LinkDirection) error {
+	_, err := c.convert(r, w, path, direction)
+	return err
+}
 
-	lines := make([]string, 0)
-	for sc.Scan() {
-		line := sc.Text()
-		line = c.convertLine(line, direction)
-		lines = append(lines, line)
+// convert converts a note like Convert and returns the number of rewritten links
+func (c *Converter) convert(r io.Reader, w io.Writer, path string, direction LinkDirection) (int, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
 	}
-	bw.WriteString(strings.Join(lines, "\n"))
-	if newLineAtEnd {
-		bw.WriteString("\n")
+	converted, links := c.convertContent(string(content), path, direction)
+	if _, err := io.WriteString(w, converted); err != nil {
+		return 0, err
 	}
+	return links, nil
+}
+
+// convertContent converts the content of the note at path and returns it with the number of rewritten links
+func (c *Converter) convertContent(content, path string, direction LinkDirection) (string, int) {
+	c = c.forNote(path)
+	doc := splitDocument(content)
+	lines := doc.lines
 
-	c.inCodeBlock = false
-	bw.Flush()
+	body := 0
+	if end := frontmatterEnd(lines); end != -1 {
+		body = end + 1
+	}
+	c.offset = len(doc.bom)
+	for i := 0; i < body; i++ {
+		c.offset += len(lines[i]) + len(doc.ends[i])
+	}
+	c.headings = documentHeadings(lines)
+	if body > 0 && c.FrontmatterMode == ConvertFrontmatter {
+		c.convertFrontmatter(lines[1:body-1], direction)
+	}
+	tokens := tokenize(lines[body:])
+	for i := body; i < len(lines); i++ {
+		c.line = i + 1
+		next := c.offset + len(lines[i]) + len(doc.ends[i])
+		if token := tokens[i-body]; token.kind == textBlock {
+			c.carry = token.carry
+			lines[i] = c.convertLine(lines[i], direction)
+		}
+		c.offset = next
+	}
+	return doc.String(), c.converted
+}
 
-	return nil
+// forNote returns a copy of the Converter to hold the state of the conversion of the note at path,
+// so that the Converter itself is never modified and can be shared across goroutines
+func (c *Converter) forNote(path string) *Converter {
+	n := *c
+	n.converted = 0
+	n.headings = nil
+	n.notePath = path
+	n.line = 0
+	n.offset = 0
+	n.carry = spanCarry{}
+	return &n
 }
 
+// convertLine converts the links of a line of text, outside the code spans
 func (c *Converter) convertLine(line string, direction LinkDirection) string {
-	if strings.HasPrefix(line, "```") {
-		c.inCodeBlock = !c.inCodeBlock
-	}
-	if c.inCodeBlock {
-		return line
+	if c.Rewrite != nil {
+		return c.rewriteLine(line, direction)
 	}
 
 	switch direction {
@@ -67,6 +185,7 @@ func (c *Converter) convertLine(line string, direction LinkDirection) string {
 func (c *Converter) convertMdToWikilink(line string) string {
 	p := Parser{
 		mdLinks: []mdLink{},
+		carry:   c.carry,
 	}
 
 	p.parse(line)
@@ -74,29 +193,84 @@ func (c *Converter) convertMdToWikilink(line string) string {
 	// start from last index to avoid index misalignment due to re-slicing
 	for i := len(p.mdLinks) - 1; i >= 0; i-- {
 		mdLink := p.mdLinks[i]
-		title, destination := mdLink.title, mdLink.destination
-		if strings.HasPrefix(destination, "http") {
+		title := mdLink.title
+		destination, fragment, hasFragment := strings.Cut(mdLink.destination, "#")
+		// wikilinks have no title, so links with one are kept to not lose it
+		if urlSchemePattern.MatchString(destination) || mdLink.hasLinkTitle {
 			continue
 		}
-
-		filename := filenameWithoutMdExtension(destination)
-
-		if filename == title {
-			if files, ok := c.filemap[filename]; ok && len(files) >= 2 {
-				relativePath := formatRelativePath(destination)
-				line = line[:mdLink.titleStartPos] + fmt.Sprintf(`[[%s|%s]]`, relativePath, title) + line[mdLink.destinationEndPos+1:]
+		if destination == "" && hasFragment {
+			if fragment == "" {
+				continue
+			}
+			// [title](#heading) -> [[#heading|title]]
+			heading := c.headingFromAnchor(c.notePath, fragment)
+			var wikilink string
+			if title == heading {
+				wikilink = fmt.Sprintf(`[[#%s]]`, heading)
 			} else {
-				line = line[:mdLink.titleStartPos] + fmt.Sprintf(`[[%s]]`, title) + line[mdLink.destinationEndPos+1:]
+				wikilink = fmt.Sprintf(`[[#%s|%s]]`, heading, title)
 			}
+			line = line[:mdLink.titleStartPos] + wikilink + line[mdLink.destinationEndPos+1:]
+			c.converted++
+			continue
+		}
+
+		relativePath, inVault := c.resolveDestination(destination)
+		file, _, found := c.findDestination(destination)
+		if !inVault && !found {
+			continue
+		}
+		if found {
+			relativePath = trimNoteExtension(c.vaultPath(file))
 		} else {
-			if files, ok := c.filemap[filename]; ok && len(files) == 1 {
-				line = line[:mdLink.titleStartPos] + fmt.Sprintf(`[[%s|%s]]`, filename, title) + line[mdLink.destinationEndPos+1:]
-			} else {
-				relativePath := formatRelativePath(destination)
-				line = line[:mdLink.titleStartPos] + fmt.Sprintf(`[[%s|%s]]`, relativePath, title) + line[mdLink.destinationEndPos+1:]
+			file = filepath.Join(c.basepath, filepath.FromSlash(relativePath)+".md")
+		}
+		decoded, _ := decodeDestination(destination)
+		filename := filenameWithoutMdExtension(decoded)
+
+		display := filename
+		var heading string
+		if fragment != "" {
+			heading = c.headingFromAnchor(file, fragment)
+			display = filename + " > " + heading
+		}
+
+		// ![alt|300](image.png) -> ![[image.png|alt|300]]
+		var size string
+		if mdLink.embed {
+			title, size = splitEmbedSize(title)
+			if title == "" {
+				title = display
 			}
 		}
 
+		var target string
+		switch c.PathStyle {
+		case AbsolutePath:
+			target = relativePath
+		case RelativePath:
+			target = strings.TrimSuffix(c.relativeFromNote(filepath.Join(c.basepath, filepath.FromSlash(relativePath))), ".md")
+		default:
+			target = relativePath
+			if found {
+				target = c.Resolver.WikilinkName(file)
+			}
+		}
+		bare := target == filename
+		if heading != "" {
+			target += "#" + heading
+		}
+
+		wikilink := target
+		if title != display || (!bare && !mdLink.embed) {
+			wikilink += "|" + title
+		}
+		if size != "" {
+			wikilink += "|" + size
+		}
+		line = line[:mdLink.titleStartPos] + fmt.Sprintf(`[[%s]]`, wikilink) + line[mdLink.destinationEndPos+1:]
+		c.converted++
 	}
 
 	return line
@@ -105,6 +279,7 @@ func (c *Converter) convertMdToWikilink(line string) string {
 func (c *Converter) convertWikilinkToMd(line string) string {
 	wp := WikilinkParser{
 		wikilinks: []wikilink{},
+		carry:     c.carry,
 	}
 
 	wp.parse(line)
@@ -112,147 +287,151 @@ func (c *Converter) convertWikilinkToMd(line string) string {
 	// start from last index to avoid index misalignment due to re-slicing
 	for i := len(wp.wikilinks) - 1; i >= 0; i-- {
 		wlink := wp.wikilinks[i]
+		sameNote := wlink.destination == "" && wlink.fragment != ""
 
-		var mdLink string
+		var path, fragment string
+		if !sameNote {
+			path = c.markdownPath(c.resolveWikilink(wlink.destination))
+		}
+		if wlink.fragment != "" {
+			fragment = "#" + formatAnchor(wlink.fragment, c.AnchorStyle)
+		}
+		destination := c.formatDestination(path, fragment)
+
+		var display string
 		if wlink.title != "" {
 			// [[destination|title]] -> [title](destination.md)
-			mdLink = fmt.Sprintf(`[%s](%s.md)`, wlink.title, wlink.destination)
+			display = wlink.title
+		} else if sameNote {
+			// [[#heading]] -> [heading](#heading)
+			display = wlink.fragment
 		} else {
 			// [[destination]] -> [destination](destination.md)
-			filename := extractFilename(wlink.destination)
-			mdLink = fmt.Sprintf(`[%s](%s.md)`, filename, wlink.destination)
+			// [[destination#heading]] -> [destination > heading](destination.md#heading)
+			display = extractFilename(wlink.destination)
+			if wlink.fragment != "" {
+				display += " > " + wlink.fragment
+			}
+		}
+		if wlink.size != "" {
+			// ![[image.png|300]] -> ![image.png|300](image.png)
+			display += "|" + wlink.size
 		}
+		mdLink := fmt.Sprintf(`[%s](%s)`, display, destination)
 
 		line = line[:wlink.startPos] + mdLink + line[wlink.endPos+1:]
+		c.converted++
 	}
 
 	return line
 }
 
-
-type Parser struct {
-	inCodeSpan bool
-	mdLinks    []mdLink
+// headingFromAnchor maps a Markdown link fragment to the heading text Obsidian expects.
+// Slugs such as #setup-steps are matched against the headings of the target note,
+// which are taken from the content being converted for links within the note.
+// Block references (#^blockid) are returned as they are.
+func (c *Converter) headingFromAnchor(file, fragment string) string {
+	if isBlockRef(fragment) {
+		return fragment
+	}
+	heading := parseAnchor(fragment)
+	headings := c.headings
+	if headings == nil || filepath.Clean(file) != filepath.Clean(c.notePath) {
+		headings = readHeadings(file)
+	}
+	for _, h := range headings {
+		if h == heading {
+			return h
+		}
+	}
+	for _, h := range headings {
+		if slugify(h) == fragment {
+			return h
+		}
+	}
+	return heading
 }
 
-type mdLink struct {
-	title               string
-	destination         string
-	titleStartPos       int
-	titleEndPos         int
-	destinationStartPos int
-	destinationEndPos   int
-}
+// resolveWikilink finds the file a wikilink target points to using the Resolver.
+// A target matching several files prefers the one next to the note.
+// When nothing matches, the target is assumed to live at the vault root, or in the attachment folder for attachments.
+// Targets with an attachment extension such as .png keep it; any other target is a note.
+func (c *Converter) resolveWikilink(target string) string {
+	if matches := c.matchWikilink(target); len(matches) > 0 {
+		return c.nearestMatch(matches)
+	}
 
-func (p *Parser) parse(input string) {
-	var currentLink *mdLink
-	for i, c := range input {
-		switch c {
-		case '`':
-			p.inCodeSpan = !p.inCodeSpan
-		case '[':
-			if p.inCodeSpan {
-				continue
-			}
-			currentLink = &mdLink{
-				titleStartPos: i,
-			}
-		case ']':
-			if p.inCodeSpan || currentLink == nil {
-				continue
-			}
-			currentLink.titleEndPos = i
-			if currentLink.destinationStartPos == 0 {
-				currentLink.title = input[currentLink.titleStartPos+1 : i]
-			}
-			if i+1 < len(input) && input[i+1] == '(' {
-				currentLink.destinationStartPos = i + 1
-			} else {
-				currentLink = nil
-			}
-		case '(':
-			if p.inCodeSpan || currentLink == nil {
-				continue
-			}
-			if currentLink.titleEndPos == 0 {
-				continue
-			}
-		case ')':
-			if p.inCodeSpan || currentLink == nil || currentLink.destinationStartPos == 0 {
-				continue
-			}
+	filename := wikilinkFilename(target)
+	if isAttachment(target) && !strings.Contains(target, "/") {
+		return filepath.Join(c.attachmentDir(), filename)
+	}
+	return filepath.Join(c.basepath, filename)
+}
 
-			currentLink.destination = input[currentLink.destinationStartPos+1 : i]
-			currentLink.destinationEndPos = i
-			p.mdLinks = append(p.mdLinks, *currentLink)
-			currentLink = nil
-		default:
-			continue
+// nearestMatch picks the file of several matches of a link that is in the same directory as the note, or the first one
+func (c *Converter) nearestMatch(matches []string) string {
+	for _, file := range matches {
+		if filepath.Dir(filepath.Clean(file)) == filepath.Dir(filepath.Clean(c.notePath)) {
+			return file
 		}
 	}
+	return matches[0]
 }
 
-// WikilinkParser parses WikiLinks in text
-type WikilinkParser struct {
-	inCodeSpan bool
-	wikilinks  []wikilink
+// matchWikilink returns the files of the vault a wikilink target in the note being converted may point to
+func (c *Converter) matchWikilink(target string) []string {
+	return c.Resolver.Resolve(c.notePath, target)
 }
 
-type wikilink struct {
-	destination string
-	title       string
-	startPos    int
-	endPos      int
+// wikilinkFilename returns the file path of a wikilink target, adding .md to notes
+func wikilinkFilename(target string) string {
+	if isAttachment(target) {
+		return filepath.FromSlash(target)
+	}
+	return filepath.FromSlash(target) + ".md"
 }
 
-func (wp *WikilinkParser) parse(input string) {
-	var currentWikilink *wikilink
-	i := 0
-
-	for i < len(input) {
-		switch input[i] {
-		case '`':
-			wp.inCodeSpan = !wp.inCodeSpan
-		case '[':
-			if wp.inCodeSpan {
-				i++
-				continue
-			}
-			// Check for [[
-			if i+1 < len(input) && input[i+1] == '[' {
-				currentWikilink = &wikilink{
-					startPos: i,
-				}
-				i += 2 // Skip [[
-				continue
-			}
-		case ']':
-			if wp.inCodeSpan || currentWikilink == nil {
-				i++
-				continue
-			}
-			// Check for ]]
-			if i+1 < len(input) && input[i+1] == ']' {
-				// Extract content between [[ and ]]
-				content := input[currentWikilink.startPos+2 : i]
-				currentWikilink.endPos = i + 1
-
-				// Parse content: check for | separator
-				if pipeIndex := strings.Index(content, "|"); pipeIndex != -1 {
-					currentWikilink.destination = strings.TrimSpace(content[:pipeIndex])
-					currentWikilink.title = strings.TrimSpace(content[pipeIndex+1:])
-				} else {
-					currentWikilink.destination = strings.TrimSpace(content)
-				}
-
-				wp.wikilinks = append(wp.wikilinks, *currentWikilink)
-				currentWikilink = nil
-				i += 2 // Skip ]]
-				continue
-			}
+// attachmentDir returns the directory new attachments of the note are stored in, following Obsidian's attachmentFolderPath:
+// "/" is the vault root, "./" the folder of the note, "./sub" a subfolder of it and any other value a folder in the vault.
+func (c *Converter) attachmentDir() string {
+	folder := c.AttachmentFolder
+	switch {
+	case folder == "" || folder == "/":
+		return c.basepath
+	case folder == "." || strings.HasPrefix(folder, "./"):
+		return filepath.Join(filepath.Dir(c.notePath), filepath.FromSlash(folder))
+	default:
+		return filepath.Join(c.basepath, filepath.FromSlash(folder))
+	}
+}
+
+// markdownPath returns the destination of a Markdown link to file according to the path style
+func (c *Converter) markdownPath(file string) string {
+	switch c.PathStyle {
+	case AbsolutePath:
+		return c.vaultPath(file)
+	case ShortestPath:
+		if !strings.Contains(c.Resolver.WikilinkName(file), "/") {
+			return filepath.Base(file)
 		}
-		i++
+		return c.vaultPath(file)
+	default:
+		return c.relativeFromNote(file)
+	}
+}
+
+// vaultPath returns the path of file from the vault root
+func (c *Converter) vaultPath(file string) string {
+	return vaultRelPath(c.basepath, file)
+}
+
+// relativeFromNote returns the path of file relative to the directory of the note being converted.
+func (c *Converter) relativeFromNote(file string) string {
+	rel, err := filepath.Rel(filepath.Dir(c.notePath), file)
+	if err != nil {
+		return filepath.ToSlash(file)
 	}
+	return filepath.ToSlash(rel)
 }
 
 // extractFilename extracts the filename from a path
@@ -48,12 +48,13 @@ https://github.com/ikorihn
 	w := &bytes.Buffer{}
 
 	c := NewConverter(
+		".",
 		map[string][]string{
 			"samename": {"./sub1/samename.md", "./sub2/samename.md"},
 			"hoge":     {"./hoge.tar.md"},
 		},
 	)
-	err := c.Convert(r, w, true, ToWikilink)
+	err := c.Convert(r, w, "note.md", ToWikilink)
 	if err != nil {
 		t.Errorf("Convert() error = %v", err)
 		return
@@ -99,8 +100,9 @@ https://github.com/ikorihn
 
 func TestConverter_convertLine(t *testing.T) {
 	type fields struct {
-		inCodeBlock bool
-		filemap     map[string][]string
+		pathStyle PathStyle
+		notePath  string
+		filemap   map[string][]string
 	}
 	type args struct {
 		line string
@@ -124,13 +126,186 @@ func TestConverter_convertLine(t *testing.T) {
 			},
 			want: "[[note with spaces|note title]] を実装する",
 		},
+		{
+			name: "destination relative to the note",
+			fields: fields{
+				notePath: "sub/a.md",
+				filemap: map[string][]string{
+					"note": {"other/note.md", "sub/note.md"},
+				},
+			},
+			args: args{
+				line: `[x](../other/note.md) and [note](../other/note.md)`,
+			},
+			want: "[[other/note|x]] and [[other/note|note]]",
+		},
+		{
+			name: "shortest disambiguating path",
+			fields: fields{
+				filemap: map[string][]string{
+					"samename": {"a/b/samename.md", "c/samename.md", "a/d/samename.md"},
+				},
+			},
+			args: args{
+				line: `[samename](a/b/samename.md) [x](c/samename.md) [y](e/samename.md)`,
+			},
+			want: "[[b/samename|samename]] [[c/samename|x]] [[e/samename|y]]",
+		},
+		{
+			name: "absolute path style",
+			fields: fields{
+				pathStyle: AbsolutePath,
+				notePath:  "sub1/a.md",
+				filemap: map[string][]string{
+					"basic":   {"basic.md"},
+					"special": {"sub1/special.md"},
+				},
+			},
+			args: args{
+				line: `[basic](../basic.md) [special](special.md) [x](special.md)`,
+			},
+			want: "[[basic]] [[sub1/special|special]] [[sub1/special|x]]",
+		},
+		{
+			name: "relative path style",
+			fields: fields{
+				pathStyle: RelativePath,
+				notePath:  "sub1/a.md",
+				filemap: map[string][]string{
+					"basic":   {"basic.md"},
+					"special": {"sub1/special.md"},
+				},
+			},
+			args: args{
+				line: `[basic](../basic.md) [special](special.md) [x](/sub1/special.md)`,
+			},
+			want: "[[../basic|basic]] [[special]] [[special|x]]",
+		},
+		{
+			name: "plus and malformed escapes in destinations",
+			fields: fields{
+				pathStyle: AbsolutePath,
+				notePath:  "note.md",
+				filemap: map[string][]string{
+					"a+b":  {"sub/a+b.md"},
+					"100%": {"sub/100%.md"},
+				},
+			},
+			args: args{
+				line: `[x](sub/a+b.md) [y](sub/100%.md) [z](sub/100%25.md)`,
+			},
+			want: "[[sub/a+b|x]] [y](sub/100%.md) [[sub/100%|z]]",
+		},
+		{
+			name: "code span with double backticks",
+			fields: fields{
+				filemap: map[string][]string{},
+			},
+			args: args{
+				line: "``a ` [x](x.md)`` [y](y.md)",
+			},
+			want: "``a ` [x](x.md)`` [[y]]",
+		},
+		{
+			name: "destination outside the vault",
+			fields: fields{
+				notePath: "sub/a.md",
+			},
+			args: args{
+				line: `[x](../../outside.md)`,
+			},
+			want: "[x](../../outside.md)",
+		},
+		{
+			name: "heading anchor",
+			fields: fields{
+				filemap: map[string][]string{
+					"note": {"note.md"},
+				},
+			},
+			args: args{
+				line: `[note > Setup steps](note.md#Setup%20steps) and [x](note.md#Setup%20steps)`,
+			},
+			want: "[[note#Setup steps]] and [[note#Setup steps|x]]",
+		},
+		{
+			name: "block reference and same-note link",
+			fields: fields{
+				filemap: map[string][]string{
+					"note": {"note.md"},
+				},
+			},
+			args: args{
+				line: `[quote](note.md#^abc123), [Heading](#Heading) and [see](#^def456)`,
+			},
+			want: "[[note#^abc123|quote]], [[#Heading]] and [[#^def456|see]]",
+		},
+		{
+			name: "embeds",
+			fields: fields{
+				notePath: "notes/a.md",
+				filemap: map[string][]string{
+					"diagram.png": {"attachments/diagram.png"},
+					"note":        {"note.md"},
+				},
+			},
+			args: args{
+				line: `![](../attachments/diagram.png) ![Diagram|300](../attachments/diagram.png) ![note](../note.md) [pdf](../doc.pdf)`,
+			},
+			want: "![[diagram.png]] ![[diagram.png|Diagram|300]] ![[note]] [[doc.pdf|pdf]]",
+		},
+		{
+			name: "angle-bracket destinations",
+			fields: fields{
+				filemap: map[string][]string{
+					"note with spaces": {"note with spaces.md"},
+					"a (1)":            {"a (1).md"},
+				},
+			},
+			args: args{
+				line: `[y](<note with spaces.md>) [z](<a (1).md>) [w](<a.md)`,
+			},
+			want: `[[note with spaces|y]] [[a (1)|z]] [w](<a.md)`,
+		},
+		{
+			name: "links with a title are kept",
+			fields: fields{
+				filemap: map[string][]string{
+					"note": {"note.md"},
+				},
+			},
+			args: args{
+				line: `[x](note.md "title") [x](note.md 'a (b)') [x](<note.md> (title)) [x](note.md)`,
+			},
+			want: `[x](note.md "title") [x](note.md 'a (b)') [x](<note.md> (title)) [[note|x]]`,
+		},
+		{
+			name: "balanced parentheses in destinations",
+			fields: fields{
+				filemap: map[string][]string{
+					"b(c)": {"b(c).md"},
+				},
+			},
+			args: args{
+				line: `[a](b(c).md) (after)`,
+			},
+			want: `[[b(c)|a]] (after)`,
+		},
+		{
+			name: "links with a URL scheme are external",
+			args: args{
+				line: `[m](mailto:a@b.com) [o](obsidian://open?vault=x) [f](file:///tmp/a.md) [h](https://example.com)`,
+			},
+			want: `[m](mailto:a@b.com) [o](obsidian://open?vault=x) [f](file:///tmp/a.md) [h](https://example.com)`,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Converter{
-				inCodeBlock: tt.fields.inCodeBlock,
-				filemap:     tt.fields.filemap,
+				PathStyle: tt.fields.pathStyle,
+				notePath:  tt.fields.notePath,
+				Resolver:  NewFileMapResolver("", tt.fields.filemap),
 			}
 			if got := c.convertLine(tt.args.line, ToWikilink); got != tt.want {
 				t.Errorf("Converter.convertLine() = %v, want %v", got, tt.want)
@@ -139,15 +314,6 @@ func TestConverter_convertLine(t *testing.T) {
 	}
 }
 
-func TestParser_readChar(t *testing.T) {
-	l := Parser{
-		mdLinks: []mdLink{},
-	}
-	l.parse("[note title](note/note%20with%20spaces.md) を実装する`")
-
-	t.Logf("%#v\n", l)
-}
-
 func TestReverseConvert(t *testing.T) {
 	r := strings.NewReader(
 		`---
@@ -188,12 +354,13 @@ https://github.com/ikorihn
 	w := &bytes.Buffer{}
 
 	c := NewConverter(
+		".",
 		map[string][]string{
 			"samename": {"./sub1/samename.md", "./sub2/samename.md"},
 			"hoge":     {"./hoge.tar.md"},
 		},
 	)
-	err := c.Convert(r, w, true, ToMarkdown)
+	err := c.Convert(r, w, "note.md", ToMarkdown)
 	if err != nil {
 		t.Errorf("ReverseConvert() error = %v", err)
 		return
@@ -215,7 +382,7 @@ https://github.com/ikorihn
 - [this is note](Note.md)
 
 [Note](Note.md) is not [the link](http://example.com)
-[Note](Note.md) and [Second Note](Second Note.md)
+[Note](Note.md) and [Second Note](Second%20Note.md)
 
 [paren in (link)](Paren.md)
 
@@ -239,8 +406,12 @@ https://github.com/ikorihn
 
 func TestReverseConverter_convertLine(t *testing.T) {
 	type fields struct {
-		inCodeBlock bool
-		filemap     map[string][]string
+		anchorStyle      AnchorStyle
+		pathStyle        PathStyle
+		encoding         EncodingPolicy
+		attachmentFolder string
+		notePath         string
+		filemap          map[string][]string
 	}
 	type args struct {
 		line string
@@ -273,7 +444,7 @@ func TestReverseConverter_convertLine(t *testing.T) {
 			args: args{
 				line: `[[note with spaces|note title]] を実装する`,
 			},
-			want: "[note title](note with spaces.md) を実装する",
+			want: "[note title](note/note%20with%20spaces.md) を実装する",
 		},
 		{
 			name: "wikilink with path",
@@ -287,11 +458,25 @@ func TestReverseConverter_convertLine(t *testing.T) {
 			},
 			want: "[samename](sub1/samename.md) and [samename](sub2/samename.md)",
 		},
+		{
+			name: "reserved characters in paths",
+			fields: fields{
+				filemap: map[string][]string{
+					"100%":        {"100%.md"},
+					"note (draft": {"sub/note (draft.md"},
+					"a <b>":       {"a <b>.md"},
+				},
+			},
+			args: args{
+				line: `[[100%]] [[note (draft]] [[a <b>]]`,
+			},
+			want: "[100%](100%25.md) [note (draft](sub/note%20%28draft.md) [a <b>](a%20%3Cb%3E.md)",
+		},
 		{
 			name: "multiple wikilinks",
 			fields: fields{
 				filemap: map[string][]string{
-					"Note": {"Note.md"},
+					"Note":  {"Note.md"},
 					"Other": {"Other.md"},
 				},
 			},
@@ -300,13 +485,163 @@ func TestReverseConverter_convertLine(t *testing.T) {
 			},
 			want: "See [Note](Note.md) and [another note](Other.md) for details.",
 		},
+		{
+			name: "shortest disambiguating path",
+			fields: fields{
+				filemap: map[string][]string{
+					"samename": {"a/b/samename.md", "c/samename.md", "a/d/samename.md"},
+				},
+			},
+			args: args{
+				line: `[[b/samename]] [[c/samename]] [[d/samename|y]]`,
+			},
+			want: "[samename](a/b/samename.md) [samename](c/samename.md) [y](a/d/samename.md)",
+		},
+		{
+			name: "shortest path style",
+			fields: fields{
+				pathStyle: ShortestPath,
+				notePath:  "sub1/a.md",
+				filemap: map[string][]string{
+					"basic":    {"basic.md"},
+					"samename": {"sub1/samename.md", "sub2/samename.md"},
+				},
+			},
+			args: args{
+				line: `[[basic]] [[sub2/samename]]`,
+			},
+			want: "[basic](basic.md) [samename](sub2/samename.md)",
+		},
+		{
+			name: "absolute path style",
+			fields: fields{
+				pathStyle: AbsolutePath,
+				notePath:  "sub1/a.md",
+				filemap: map[string][]string{
+					"basic":   {"basic.md"},
+					"special": {"sub1/special.md"},
+				},
+			},
+			args: args{
+				line: `[[basic]] [[special]]`,
+			},
+			want: "[basic](basic.md) [special](sub1/special.md)",
+		},
+		{
+			name: "attachment folder next to the note",
+			fields: fields{
+				attachmentFolder: "./assets",
+				notePath:         "sub1/a.md",
+				filemap: map[string][]string{
+					"diagram.png": {"diagram.png"},
+				},
+			},
+			args: args{
+				line: `![[diagram.png]] ![[new.png]]`,
+			},
+			want: "![diagram.png](../diagram.png) ![new.png](assets/new.png)",
+		},
+		{
+			name: "code span with double backticks",
+			fields: fields{
+				filemap: map[string][]string{},
+			},
+			args: args{
+				line: "``a ` [[x]]`` [[y]]",
+			},
+			want: "``a ` [[x]]`` [y](y.md)",
+		},
+		{
+			name: "relative to the note",
+			fields: fields{
+				notePath: "sub1/a.md",
+				filemap: map[string][]string{
+					"basic":    {"basic.md"},
+					"samename": {"sub1/samename.md", "sub2/samename.md"},
+				},
+			},
+			args: args{
+				line: `[[basic]], [[samename]], [[sub2/samename]] and [[missing]]`,
+			},
+			want: "[basic](../basic.md), [samename](samename.md), [samename](../sub2/samename.md) and [missing](../missing.md)",
+		},
+		{
+			name: "heading anchor",
+			fields: fields{
+				filemap: map[string][]string{
+					"note": {"note.md"},
+				},
+			},
+			args: args{
+				line: `[[note#Setup steps]] and [[note#Setup steps|x]]`,
+			},
+			want: "[note > Setup steps](note.md#Setup%20steps) and [x](note.md#Setup%20steps)",
+		},
+		{
+			name: "heading anchor in GitHub style",
+			fields: fields{
+				anchorStyle: GitHubAnchor,
+				filemap: map[string][]string{
+					"note": {"note.md"},
+				},
+			},
+			args: args{
+				line: `[[note#Setup steps]]`,
+			},
+			want: "[note > Setup steps](note.md#setup-steps)",
+		},
+		{
+			name: "block reference and same-note link",
+			fields: fields{
+				anchorStyle: GitHubAnchor,
+				filemap: map[string][]string{
+					"note": {"note.md"},
+				},
+			},
+			args: args{
+				line: `[[note#^abc123|quote]], [[#Setup steps]] and [[#^def456]]`,
+			},
+			want: "[quote](note.md#^abc123), [Setup steps](#setup-steps) and [^def456](#^def456)",
+		},
+		{
+			name: "angle bracket encoding",
+			fields: fields{
+				encoding: AngleBrackets,
+				filemap: map[string][]string{
+					"note with spaces": {"note/note with spaces.md"},
+					"100%":             {"100%.md"},
+				},
+			},
+			args: args{
+				line: `[[note with spaces#Setup steps|setup]] [[note with spaces]] [[100%]]`,
+			},
+			want: "[setup](<note/note with spaces.md#Setup%20steps>) [note with spaces](<note/note with spaces.md>) [100%](100%25.md)",
+		},
+		{
+			name: "embeds",
+			fields: fields{
+				notePath: "notes/a.md",
+				filemap: map[string][]string{
+					"diagram.png": {"attachments/diagram.png"},
+					"note":        {"note.md"},
+				},
+			},
+			args: args{
+				line: `![[diagram.png]] ![[diagram.png|300]] ![[diagram.png|Diagram|300x200]] ![[note]] [[missing.pdf]]`,
+			},
+			want: "![diagram.png](../attachments/diagram.png) ![diagram.png|300](../attachments/diagram.png) ![Diagram|300x200](../attachments/diagram.png) ![note](../note.md) [missing.pdf](../missing.pdf)",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Converter{
-				inCodeBlock: tt.fields.inCodeBlock,
-				filemap:     tt.fields.filemap,
+				AnchorStyle:      tt.fields.anchorStyle,
+				PathStyle:        tt.fields.pathStyle,
+				Encoding:         tt.fields.encoding,
+				AttachmentFolder: tt.fields.attachmentFolder,
+				notePath:         tt.fields.notePath,
+				Resolver:         NewFileMapResolver("", tt.fields.filemap),
 			}
 			if got := c.convertLine(tt.args.line, ToMarkdown); got != tt.want {
 				t.Errorf("Converter.convertLine() = %v, want %v", got, tt.want)
@@ -315,19 +650,6 @@ func TestReverseConverter_convertLine(t *testing.T) {
 	}
 }
 
-func TestWikilinkParser_parse(t *testing.T) {
-	wp := WikilinkParser{
-		wikilinks: []wikilink{},
-	}
-	wp.parse("[[note with spaces|note title]] を実装する")
-
-	assert.Len(t, wp.wikilinks, 1)
-	assert.Equal(t, "note with spaces", wp.wikilinks[0].destination)
-	assert.Equal(t, "note title", wp.wikilinks[0].title)
-	assert.Equal(t, 0, wp.wikilinks[0].startPos)
-	assert.Equal(t, 30, wp.wikilinks[0].endPos)
-}
-
 func TestExtractFilename(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,125 @@
+package olconv
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// decodeDestination decodes the percent-encoding of a Markdown link destination.
+// Unlike a query, a path keeps + as it is. It returns false for a malformed escape, such as a bare %.
+func decodeDestination(destination string) (string, bool) {
+	decoded, err := url.PathUnescape(destination)
+	return decoded, err == nil
+}
+
+// resolveDestination resolves a Markdown link destination against the directory of the note
+// being converted and returns it as a vault-relative path without the .md extension.
+// It returns false when the destination points outside the vault or cannot be decoded.
+func (c *Converter) resolveDestination(destination string) (string, bool) {
+	if destination == "" {
+		return "", true
+	}
+	destination, ok := decodeDestination(destination)
+	if !ok {
+		return "", false
+	}
+
+	var resolved string
+	if strings.HasPrefix(destination, "/") {
+		resolved = filepath.Join(c.basepath, filepath.FromSlash(strings.TrimPrefix(destination, "/")))
+	} else {
+		resolved = filepath.Join(filepath.Dir(c.notePath), filepath.FromSlash(destination))
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(c.basepath), resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return strings.TrimSuffix(filepath.ToSlash(rel), ".md"), true
+}
+
+// findDestination returns the file of the vault a Markdown link destination in the note being converted points to.
+// Like Obsidian, the destination is looked up relative to the note, then from the vault root,
+// and finally by name through the Resolver, which finds the destinations written with the shortest or absolute path.
+// fallback reports that the file was not found relative to the note.
+func (c *Converter) findDestination(destination string) (file string, fallback bool, ok bool) {
+	if relativePath, ok := c.resolveDestination(destination); ok {
+		if file, ok := c.lookupDestination(relativePath); ok {
+			return file, false, true
+		}
+	}
+
+	decoded, ok := decodeDestination(destination)
+	if !ok {
+		return "", false, false
+	}
+	rootPath := path.Clean(strings.TrimPrefix(decoded, "/"))
+	if rootPath == ".." || strings.HasPrefix(rootPath, "../") {
+		return "", false, false
+	}
+	if file, ok := c.lookupDestination(strings.TrimSuffix(rootPath, ".md")); ok {
+		return file, true, true
+	}
+
+	matches := c.matchWikilink(strings.TrimSuffix(rootPath, ".md"))
+	if len(matches) == 0 {
+		return "", false, false
+	}
+	return c.nearestMatch(matches), true, true
+}
+
+// lookupDestination returns the file of the vault a vault-relative path, as returned by resolveDestination, refers to
+func (c *Converter) lookupDestination(vaultPath string) (string, bool) {
+	for _, file := range c.matchWikilink(vaultPath) {
+		if trimNoteExtension(c.vaultPath(file)) == vaultPath {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// EncodingPolicy selects how the paths of converted Markdown links are written
+type EncodingPolicy int
+
+const (
+	// PercentEncoding percent-encodes spaces like Obsidian does, e.g. [a](my%20note.md)
+	PercentEncoding EncodingPolicy = iota
+	// AngleBrackets keeps spaces and writes the destination between angle brackets, e.g. [a](<my note.md>)
+	AngleBrackets
+)
+
+// ParseEncodingPolicy converts a flag value into an EncodingPolicy
+func ParseEncodingPolicy(s string) (EncodingPolicy, bool) {
+	switch s {
+	case "percent":
+		return PercentEncoding, true
+	case "angle":
+		return AngleBrackets, true
+	default:
+		return PercentEncoding, false
+	}
+}
+
+// destinationEscaper percent-encodes the characters of a path that end a Markdown link destination,
+// such as spaces and unbalanced parentheses, or that would be decoded when reading it back, like %
+var destinationEscaper = strings.NewReplacer("%", "%25", " ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
+
+// angleDestinationEscaper percent-encodes the characters of a path that cannot be written in a <...> destination
+var angleDestinationEscaper = strings.NewReplacer("%", "%25", "<", "%3C", ">", "%3E")
+
+// encodeDestination writes a path of the vault as a Markdown link destination, percent-encoding spaces like Obsidian does
+func encodeDestination(path string) string {
+	return destinationEscaper.Replace(path)
+}
+
+// formatDestination writes a path of the vault followed by a link fragment as a Markdown link destination,
+// following the EncodingPolicy of the Converter
+func (c *Converter) formatDestination(path, fragment string) string {
+	if c.Encoding == AngleBrackets && strings.Contains(path, " ") {
+		return "<" + angleDestinationEscaper.Replace(path) + fragment + ">"
+	}
+	return encodeDestination(path) + fragment
+}
@@ -0,0 +1,228 @@
+package olconv
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change
+const diffContextLines = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff returns the changes between two versions of a file in unified diff format.
+// It returns an empty string when both versions are identical.
+func unifiedDiff(path, before, after string) string {
+	if before == after {
+		return ""
+	}
+
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+
+	for start := 0; start < len(ops); {
+		// find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// extend the hunk while changes are close enough to share context
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContextLines {
+				break
+			}
+		}
+
+		hunkStart := max(start-diffContextLines, 0)
+		hunkEnd := min(end+diffContextLines, len(ops))
+		writeHunk(&b, ops, hunkStart, hunkEnd)
+		start = hunkEnd
+	}
+
+	return b.String()
+}
+
+// writeHunk writes ops[start:end] as a single hunk
+func writeHunk(b *strings.Builder, ops []diffOp, start, end int) {
+	beforeLine, afterLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			beforeLine++
+		}
+		if op.kind != '-' {
+			afterLine++
+		}
+	}
+
+	beforeCount, afterCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			beforeCount++
+		}
+		if op.kind != '-' {
+			afterCount++
+		}
+	}
+	if beforeCount == 0 {
+		beforeLine--
+	}
+	if afterCount == 0 {
+		afterLine--
+	}
+
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", beforeLine, beforeCount, afterLine, afterCount)
+	for _, op := range ops[start:end] {
+		b.WriteByte(op.kind)
+		b.WriteString(op.line)
+		b.WriteByte('\n')
+	}
+}
+
+// splitLines splits text into lines, dropping the empty element after a final newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the shortest list of changes turning a into b, with the linear space variant of Myers' algorithm.
+// Memory grows with the size of the note, not with the number of changes, so a note with thousands of rewritten
+// links is diffed as cheaply as a note where a Rewrite hook adds a few lines.
+func diffLines(a, b []string) []diffOp {
+	size := (len(a)+len(b)+1)/2 + 1
+	d := lineDiff{
+		a:        a,
+		b:        b,
+		ops:      make([]diffOp, 0, len(a)+len(b)),
+		forward:  make([]int, 2*size+1),
+		backward: make([]int, 2*size+1),
+		offset:   size,
+	}
+	d.compare(0, len(a), 0, len(b))
+
+	// a run of changed lines is written as its removed lines followed by its added lines
+	for start := 0; start < len(d.ops); {
+		if d.ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		end := start
+		for end < len(d.ops) && d.ops[end].kind != ' ' {
+			end++
+		}
+		slices.SortStableFunc(d.ops[start:end], func(x, y diffOp) int {
+			return int(y.kind) - int(x.kind)
+		})
+		start = end
+	}
+	return d.ops
+}
+
+// lineDiff holds the state of diffLines. The forward and backward paths are shared by every call to compare,
+// as they are only used while finding a middle snake.
+type lineDiff struct {
+	a, b              []string
+	ops               []diffOp
+	forward, backward []int
+	offset            int
+}
+
+// compare appends the changes turning a[aStart:aEnd] into b[bStart:bEnd]
+func (d *lineDiff) compare(aStart, aEnd, bStart, bEnd int) {
+	for aStart < aEnd && bStart < bEnd && d.a[aStart] == d.b[bStart] {
+		d.ops = append(d.ops, diffOp{kind: ' ', line: d.a[aStart]})
+		aStart++
+		bStart++
+	}
+	common := 0
+	for aStart < aEnd-common && bStart < bEnd-common && d.a[aEnd-common-1] == d.b[bEnd-common-1] {
+		common++
+	}
+	aEnd -= common
+	bEnd -= common
+
+	switch {
+	case aStart == aEnd:
+		for _, line := range d.b[bStart:bEnd] {
+			d.ops = append(d.ops, diffOp{kind: '+', line: line})
+		}
+	case bStart == bEnd:
+		for _, line := range d.a[aStart:aEnd] {
+			d.ops = append(d.ops, diffOp{kind: '-', line: line})
+		}
+	default:
+		x, y := d.middleSnake(aStart, aEnd, bStart, bEnd)
+		d.compare(aStart, x, bStart, y)
+		d.compare(x, aEnd, y, bEnd)
+	}
+
+	for _, line := range d.a[aEnd : aEnd+common] {
+		d.ops = append(d.ops, diffOp{kind: ' ', line: line})
+	}
+}
+
+// middleSnake returns a point of a shortest path from (aStart, bStart) to (aEnd, bEnd), other than its ends,
+// by following the shortest paths from both ends until they overlap.
+// Both ranges must be non-empty and differ in their first and last lines.
+func (d *lineDiff) middleSnake(aStart, aEnd, bStart, bEnd int) (int, int) {
+	n, m := aEnd-aStart, bEnd-bStart
+	delta := n - m
+	// forward[k] is the furthest x reached on the diagonal k = x - y from the start,
+	// backward[k] the furthest distance from the end reached on the diagonal delta - k
+	forward, backward, offset := d.forward, d.backward, d.offset
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	for depth := 0; depth <= (n+m+1)/2; depth++ {
+		for k := -depth; k <= depth; k += 2 {
+			x := forward[offset+k-1] + 1
+			if k == -depth || (k != depth && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aStart+x] == d.b[bStart+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			if delta%2 != 0 && delta-k >= -(depth-1) && delta-k <= depth-1 && x+backward[offset+delta-k] >= n {
+				return aStart + startX, bStart + startY
+			}
+		}
+
+		for k := -depth; k <= depth; k += 2 {
+			x := backward[offset+k-1] + 1
+			if k == -depth || (k != depth && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aEnd-x-1] == d.b[bEnd-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			if delta%2 == 0 && delta-k >= -depth && delta-k <= depth && x+forward[offset+delta-k] >= n {
+				return aEnd - startX, bEnd - startY
+			}
+		}
+	}
+	// the paths always overlap after (n+m+1)/2 steps
+	panic("olconv: no middle snake")
+}
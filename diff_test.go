@@ -0,0 +1,100 @@
+package olconv
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{
+			name:   "identical",
+			before: "a\nb\n",
+			after:  "a\nb\n",
+			want:   "",
+		},
+		{
+			name:   "changed line with context",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			after:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- a/note.md\n+++ b/note.md\n" +
+				"@@ -2,7 +2,7 @@\n" +
+				" 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:   "separate hunks",
+			before: "a\n1\n2\n3\n4\n5\n6\n7\n8\nb\n",
+			after:  "A\n1\n2\n3\n4\n5\n6\n7\n8\nB\n",
+			want: "--- a/note.md\n+++ b/note.md\n" +
+				"@@ -1,4 +1,4 @@\n" +
+				"-a\n+A\n 1\n 2\n 3\n" +
+				"@@ -7,4 +7,4 @@\n" +
+				" 6\n 7\n 8\n-b\n+B\n",
+		},
+		{
+			// a Rewrite hook may turn a link into several lines
+			name:   "added line",
+			before: "1\n2\n[a](a.md)\n4\n5\n6\n7\n8\n9\n[b](b.md)\n",
+			after:  "1\n2\n[[a]]\n> quoted\n4\n5\n6\n7\n8\n9\n[[b]]\n",
+			want: "--- a/note.md\n+++ b/note.md\n" +
+				"@@ -1,10 +1,11 @@\n" +
+				" 1\n 2\n-[a](a.md)\n+[[a]]\n+> quoted\n 4\n 5\n 6\n 7\n 8\n 9\n-[b](b.md)\n+[[b]]\n",
+		},
+		{
+			name:   "removed line",
+			before: "a\nb\nc\n",
+			after:  "a\nc\n",
+			want: "--- a/note.md\n+++ b/note.md\n" +
+				"@@ -1,3 +1,2 @@\n" +
+				" a\n-b\n c\n",
+		},
+		{
+			name:   "insertion into empty file",
+			before: "",
+			after:  "new\n",
+			want: "--- a/note.md\n+++ b/note.md\n" +
+				"@@ -0,0 +1,1 @@\n" +
+				"+new\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, unifiedDiff("note.md", tt.before, tt.after))
+		})
+	}
+}
+
+func TestUnifiedDiff_LargeNote(t *testing.T) {
+	// an index note with a link on every line
+	var before, after, want strings.Builder
+	want.WriteString("--- a/index.md\n+++ b/index.md\n@@ -1,5000 +1,5000 @@\n")
+	for i := range 5000 {
+		fmt.Fprintf(&before, "- [note %d](note%d.md)\n", i, i)
+		fmt.Fprintf(&after, "- [[note%d|note %d]]\n", i, i)
+	}
+	for i := range 5000 {
+		fmt.Fprintf(&want, "-- [note %d](note%d.md)\n", i, i)
+	}
+	for i := range 5000 {
+		fmt.Fprintf(&want, "+- [[note%d|note %d]]\n", i, i)
+	}
+
+	var start, end runtime.MemStats
+	runtime.ReadMemStats(&start)
+	got := unifiedDiff("index.md", before.String(), after.String())
+	runtime.ReadMemStats(&end)
+
+	assert.Equal(t, want.String(), got)
+	// memory grows with the size of the note, not with its square
+	assert.Less(t, end.TotalAlloc-start.TotalAlloc, uint64(16<<20))
+}
@@ -0,0 +1,52 @@
+package olconv
+
+import "strings"
+
+// utf8BOM is the byte order mark some editors write at the start of UTF-8 files
+const utf8BOM = "\ufeff"
+
+// document is the content of a note split into lines, keeping what is needed to write it back byte for byte
+type document struct {
+	// bom is the byte order mark the content starts with, if any
+	bom string
+	// lines are the lines without their line ending
+	lines []string
+	// ends are the line endings of the lines, "\n", "\r\n", or "" for a last line without one
+	ends []string
+}
+
+// splitDocument splits content into lines, separating the byte order mark and the line endings
+func splitDocument(content string) document {
+	doc := document{}
+	if strings.HasPrefix(content, utf8BOM) {
+		doc.bom = utf8BOM
+		content = content[len(utf8BOM):]
+	}
+
+	for content != "" {
+		line, rest, found := strings.Cut(content, "\n")
+		end := ""
+		if found {
+			end = "\n"
+			if strings.HasSuffix(line, "\r") {
+				line = line[:len(line)-1]
+				end = "\r\n"
+			}
+		}
+		doc.lines = append(doc.lines, line)
+		doc.ends = append(doc.ends, end)
+		content = rest
+	}
+	return doc
+}
+
+// String joins the lines back with their line endings and byte order mark
+func (d document) String() string {
+	var sb strings.Builder
+	sb.WriteString(d.bom)
+	for i, line := range d.lines {
+		sb.WriteString(line)
+		sb.WriteString(d.ends[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+package olconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    document
+	}{
+		{"empty", "", document{}},
+		{"no final newline", "a\nb", document{lines: []string{"a", "b"}, ends: []string{"\n", ""}}},
+		{"final newline", "a\nb\n", document{lines: []string{"a", "b"}, ends: []string{"\n", "\n"}}},
+		{"crlf", "a\r\n\r\nb\r\n", document{lines: []string{"a", "", "b"}, ends: []string{"\r\n", "\r\n", "\r\n"}}},
+		{"mixed", "a\r\nb\nc\r", document{lines: []string{"a", "b", "c\r"}, ends: []string{"\r\n", "\n", ""}}},
+		{"bom", "\ufeff---\r\n", document{bom: "\ufeff", lines: []string{"---"}, ends: []string{"\r\n"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := splitDocument(tt.content)
+			assert.Equal(t, tt.want, doc)
+			assert.Equal(t, tt.content, doc.String())
+		})
+	}
+}
+
+func TestConvert_PreservesBytes(t *testing.T) {
+	c := NewConverter("vault", map[string][]string{"note": {"vault/note.md"}}, WithFrontmatterMode(ConvertFrontmatter))
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "crlf",
+			input: "---\r\nup: \"[[note]]\"\r\n---\r\n# Title\r\n\r\n[[note]]  \r\n```\r\n[[note]]\r\n```\r\n",
+			want:  "---\r\nup: \"[note](note.md)\"\r\n---\r\n# Title\r\n\r\n[note](note.md)  \r\n```\r\n[[note]]\r\n```\r\n",
+		},
+		{
+			name:  "mixed line endings",
+			input: "[[note]]\r\n[[note]]\n\n[[note]]\r\n",
+			want:  "[note](note.md)\r\n[note](note.md)\n\n[note](note.md)\r\n",
+		},
+		{
+			name:  "bom before frontmatter",
+			input: "\ufeff---\nup: \"[[note]]\"\n---\n[[note]]\n",
+			want:  "\ufeff---\nup: \"[note](note.md)\"\n---\n[note](note.md)\n",
+		},
+		{
+			name:  "no final newline",
+			input: "[[note]]\t\n\n[[note]]",
+			want:  "[note](note.md)\t\n\n[note](note.md)",
+		},
+		{
+			name:  "blank lines at end",
+			input: "[[note]]\n\n\n",
+			want:  "[note](note.md)\n\n\n",
+		},
+		{
+			name:  "long line",
+			input: strings.Repeat("x", 100*1024) + " [[note]]\n",
+			want:  strings.Repeat("x", 100*1024) + " [note](note.md)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			require.NoError(t, c.Convert(strings.NewReader(tt.input), w, "vault/index.md", ToMarkdown))
+			assert.Equal(t, tt.want, w.String())
+
+			// converting back only changes the links again
+			w2 := &bytes.Buffer{}
+			require.NoError(t, c.Convert(strings.NewReader(tt.want), w2, "vault/index.md", ToWikilink))
+			assert.Equal(t, tt.input, w2.String())
+		})
+	}
+}
+
+func TestExtractLinks_LineEndings(t *testing.T) {
+	doc := "\ufeff# Title\r\n\r\n[[a]] and [b](b.md)\r\n"
+	links, err := ExtractLinks(strings.NewReader(doc))
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	for _, link := range links {
+		assert.Equal(t, link.Raw, doc[link.Offset:link.End])
+		assert.Equal(t, 3, link.Line)
+	}
+	assert.Equal(t, 1, links[0].Column)
+	assert.Equal(t, 11, links[1].Column)
+}
+
+func TestConvert_MultilineCode(t *testing.T) {
+	c := NewConverter("vault", map[string][]string{"x": {"vault/x.md"}})
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "code span over two lines",
+			input: "text `code\n[[x]] more` end [[x]]\n",
+			want:  "text `code\n[[x]] more` end [x](x.md)\n",
+		},
+		{
+			name:  "indented code in a list item",
+			input: "- a\n\n      [[x]]\n\n  [[x]]\n",
+			want:  "- a\n\n      [[x]]\n\n  [x](x.md)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			require.NoError(t, c.Convert(strings.NewReader(tt.input), w, "vault/index.md", ToMarkdown))
+			assert.Equal(t, tt.want, w.String())
+
+			links, err := ExtractLinks(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Len(t, links, 1)
+		})
+	}
+}
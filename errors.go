@@ -0,0 +1,31 @@
+package olconv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FileError is an error that occurred while converting a file of the vault
+type FileError struct {
+	// File is the path of the file, as listed in Summary.Files
+	File string
+	Err  error
+}
+
+// Error formats the error as file: message
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// fileError wraps err with the file it occurred in, unless it is already a FileError
+func fileError(file string, err error) error {
+	var fe *FileError
+	if errors.As(err, &fe) {
+		return err
+	}
+	return &FileError{File: file, Err: err}
+}
@@ -0,0 +1,70 @@
+package olconv
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileError(t *testing.T) {
+	err := fileError("notes/a.md", fs.ErrPermission)
+	assert.EqualError(t, err, "notes/a.md: permission denied")
+	assert.ErrorIs(t, err, fs.ErrPermission)
+
+	// errors already about a file are kept as they are
+	fileErr := &FileError{File: "notes/b.md", Err: errors.New("is a directory")}
+	assert.Same(t, fileErr, fileError("notes/a.md", fileErr))
+	assert.EqualError(t, fileErr, "notes/b.md: is a directory")
+}
+
+func TestConvertVault_ContinueOnError(t *testing.T) {
+	newVault := func(t *testing.T) string {
+		dir := writeVault(t, map[string]string{
+			"a.md": "[B](b.md)\n",
+			"b.md": "[A](a.md)\n",
+		})
+		// a note that cannot be read
+		require.NoError(t, os.Symlink(filepath.Join(dir, "missing.md"), filepath.Join(dir, "ab.md")))
+		return dir
+	}
+
+	// the conversion stops at the first error
+	dir := newVault(t)
+	summary, err := ConvertVault(context.Background(), dir, Options{Direction: ToWikilink})
+	var fe *FileError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, filepath.Join(dir, "ab.md"), fe.File)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Equal(t, []string{filepath.Join(dir, "a.md")}, summary.Files)
+	assert.Equal(t, map[string]string{"a.md": "[[b|B]]\n", "b.md": "[A](a.md)\n"}, readVault(t, dir, "a.md", "b.md"))
+
+	// or goes on with the other notes
+	dir = newVault(t)
+	summary, err = ConvertVault(context.Background(), dir, Options{Direction: ToWikilink, ContinueOnError: true})
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, filepath.Join(dir, "ab.md"), fe.File)
+	assert.Equal(t, []string{filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")}, summary.Files)
+	assert.Equal(t, []string{filepath.Join(dir, "ab.md")}, summary.Failed)
+	assert.Equal(t, map[string]string{"a.md": "[[b|B]]\n", "b.md": "[[a|A]]\n"}, readVault(t, dir, "a.md", "b.md"))
+}
+
+func TestConvertVault_LongLine(t *testing.T) {
+	// notes are converted as a whole, so no line is too long to convert
+	long := strings.Repeat("x", 100*1024)
+	dir := writeVault(t, map[string]string{
+		"long.md": "# Title\n\n" + long + " [[b]]\n",
+		"b.md":    "# B\n",
+	})
+
+	summary, err := ConvertVault(context.Background(), dir, Options{Direction: ToMarkdown})
+	require.NoError(t, err)
+	assert.Empty(t, summary.Failed)
+	assert.Equal(t, "# Title\n\n"+long+" [b](b.md)\n", readVault(t, dir, "long.md")["long.md"])
+}
@@ -2,101 +2,280 @@ package olconv
 
 import (
 	"bytes"
-	"net/url"
+	"context"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 )
 
-func LinkToWikilink(basepath string) error {
+// ConvertVault converts the links of every note of the vault at root as configured by opts.
+// The link settings of the vault's Obsidian configuration are used for the options left unset.
+// With DryRun, nothing is written; the returned Summary describes the changes in both cases.
+//
+// Errors about a note are *FileError. The conversion stops at the first one, or with ContinueOnError
+// goes on with the other notes and returns every error joined with errors.Join.
+// When ctx is done, the conversion stops between two notes; the notes already written are listed in the Summary.
+func ConvertVault(ctx context.Context, root string, opts Options) (Summary, error) {
+	summary := Summary{Files: []string{}, Failed: []string{}}
 
-	files, err := ListMdFiles(basepath)
+	files, c, err := newVaultConverter(root, opts)
 	if err != nil {
-		panic(err)
+		return summary, err
 	}
-	filemap := FileListToMap(files)
-	c := NewConverter(filemap)
 
-	for _, file := range files {
-		newLineAtEnd := false
-		content, err := os.ReadFile(file)
-		if err != nil {
+	errs := make([]error, 0)
+	fail := func(file string, err error) error {
+		err = fileError(file, err)
+		if !opts.ContinueOnError {
 			return err
 		}
-		if len(content) == 0 {
-			continue
+		summary.Failed = append(summary.Failed, file)
+		errs = append(errs, err)
+		return nil
+	}
+
+	err = convertFiles(ctx, c, files, opts.Direction, opts.Jobs, func(file string, r fileResult) error {
+		if r.err != nil {
+			return fail(file, r.err)
 		}
-		if content[len(content)-1] == '\n' {
-			newLineAtEnd = true
+		if bytes.Equal(r.content, r.converted) {
+			return nil
 		}
 
-		f, err := os.Open(file)
-		if err != nil {
-			return err
+		if opts.Diff != nil {
+			name, err := filepath.Rel(root, file)
+			if err != nil {
+				name = file
+			}
+			if _, err := io.WriteString(opts.Diff, unifiedDiff(filepath.ToSlash(name), string(r.content), string(r.converted))); err != nil {
+				return err
+			}
+		}
+		if !opts.DryRun {
+			if opts.Journal != nil {
+				// a note whose original content cannot be recorded is left unchanged
+				if err := opts.Journal.Record(file, r.content, r.converted); err != nil {
+					return fail(file, err)
+				}
+			}
+			if err := writeFileAtomic(file, r.converted, opts.Mtime); err != nil {
+				return fail(file, err)
+			}
 		}
+		summary.Files = append(summary.Files, file)
+		summary.Links += r.links
+		return nil
+	})
 
-		buf := &bytes.Buffer{}
-		if err := c.Convert(f, buf, newLineAtEnd, ToWikilink); err != nil {
-			return err
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return summary, errors.Join(errs...)
+}
+
+// fileResult is the outcome of the conversion of a note: its original content, the converted content
+// and the number of rewritten links, or the error that occurred
+type fileResult struct {
+	content, converted []byte
+	links              int
+	err                error
+}
+
+// convertFiles converts the notes with up to jobs goroutines, or GOMAXPROCS when jobs is not positive,
+// and passes the results to yield one at a time in the order of files.
+// At most twice as many notes as goroutines are held in memory waiting for yield.
+// It stops at the first error of yield, or when ctx is done.
+func convertFiles(ctx context.Context, c *Converter, files []string, direction LinkDirection, jobs int, yield func(file string, r fileResult) error) error {
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+
+	results := make([]chan fileResult, len(files))
+	for i := range results {
+		results[i] = make(chan fileResult, 1)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// window limits how far the workers get ahead of yield
+	window := make(chan struct{}, 2*jobs)
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range files {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case next <- i:
+			case <-ctx.Done():
+				return
+			}
 		}
-		f.Close()
+	}()
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				var r fileResult
+				r.content, r.converted, r.links, r.err = convertFile(c, files[i], direction)
+				results[i] <- r
+			}
+		}()
+	}
 
-		wf, err := os.OpenFile(file, os.O_WRONLY|os.O_TRUNC, 0644)
-		if err != nil {
+	for i, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var r fileResult
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-window
+		if err := yield(file, r); err != nil {
 			return err
 		}
-		wf.WriteString(buf.String())
-		wf.Close()
 	}
-
 	return nil
-
 }
 
-func WikilinkToLink(basepath string) error {
-	files, err := ListMdFiles(basepath)
+// ConvertReader converts a single note read from r into w, resolving its links against the vault at root.
+// path is the location of the note, which does not need to exist; an empty path puts the note at the vault root.
+// The options that only apply to ConvertVault are ignored.
+func ConvertReader(root, path string, r io.Reader, w io.Writer, opts Options) error {
+	opts.Files = nil
+	_, c, err := newVaultConverter(root, opts)
 	if err != nil {
 		return err
 	}
-	filemap := FileListToMap(files)
-	c := NewConverter(filemap)
 
-	for _, file := range files {
-		newLineAtEnd := false
-		content, err := os.ReadFile(file)
-		if err != nil {
+	note := filepath.Join(root, "untitled.md")
+	if path != "" {
+		if note, err = vaultFile(root, path); err != nil {
 			return err
 		}
-		if len(content) == 0 {
-			continue
-		}
-		if content[len(content)-1] == '\n' {
-			newLineAtEnd = true
-		}
+	}
 
-		f, err := os.Open(file)
-		if err != nil {
-			return err
-		}
+	_, err = c.convert(r, w, note, opts.Direction)
+	return err
+}
 
-		buf := &bytes.Buffer{}
-		if err := c.Convert(f, buf, newLineAtEnd, ToMarkdown); err != nil {
-			return err
+// LinkToWikilink converts Markdown links to wikilinks in every note of the vault.
+//
+// Deprecated: use ConvertVault.
+func LinkToWikilink(basepath string) error {
+	_, err := ConvertVault(context.Background(), basepath, Options{Direction: ToWikilink})
+	return err
+}
+
+// WikilinkToLink converts wikilinks to Markdown links in every note of the vault.
+//
+// Deprecated: use ConvertVault.
+func WikilinkToLink(basepath string) error {
+	_, err := ConvertVault(context.Background(), basepath, Options{Direction: ToMarkdown})
+	return err
+}
+
+// Summary describes the changes made, or that would be made, by a vault conversion
+type Summary struct {
+	// Files lists the notes whose content changed
+	Files []string
+	// Links is the number of rewritten links
+	Links int
+	// Failed lists the notes left unchanged because of an error, with ContinueOnError
+	Failed []string
+}
+
+// newVaultConverter lists the notes of a vault selected by opts and prepares a Converter that resolves links against the files in it.
+// The link settings of the vault's Obsidian configuration are used for the path style and attachment folder left unset in opts.
+func newVaultConverter(basepath string, opts Options) ([]string, *Converter, error) {
+	filter, err := newVaultFilter(basepath, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, vaultFiles, err := filter.list()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(opts.Files) > 0 {
+		if files, err = filter.selectFiles(opts.Files); err != nil {
+			return nil, nil, err
 		}
-		f.Close()
+	}
+	config, err := ReadObsidianConfig(basepath)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		wf, err := os.OpenFile(file, os.O_WRONLY|os.O_TRUNC, 0644)
-		if err != nil {
-			return err
+	if config != nil {
+		if opts.PathStyle == DefaultPath {
+			opts.PathStyle = config.PathStyle()
+		}
+		if opts.AttachmentFolder == "" {
+			opts.AttachmentFolder = config.AttachmentFolderPath
 		}
-		wf.WriteString(buf.String())
-		wf.Close()
 	}
+	return files, NewConverter(basepath, FileListToMap(vaultFiles), WithOptions(opts)), nil
+}
 
-	return nil
+// convertFile converts the links of a note and returns its original content,
+// the converted content and the number of rewritten links
+func convertFile(c *Converter, file string, direction LinkDirection) ([]byte, []byte, int, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	converted, links := c.convertContent(string(content), file, direction)
+	return content, []byte(converted), links, nil
+}
+
+// attachmentExtensions are the non-Markdown file types Obsidian can link to and embed
+var attachmentExtensions = map[string]bool{
+	// images
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".svg": true, ".webp": true, ".avif": true,
+	// audio
+	".mp3": true, ".wav": true, ".m4a": true, ".ogg": true, ".flac": true, ".3gp": true,
+	// video
+	".mp4": true, ".webm": true, ".mov": true, ".mkv": true, ".ogv": true,
+	// documents
+	".pdf": true, ".canvas": true,
+}
+
+// isAttachment reports whether the path has an attachment extension
+func isAttachment(path string) bool {
+	return attachmentExtensions[strings.ToLower(filepath.Ext(path))]
 }
 
 func ListMdFiles(basepath string) ([]string, error) {
+	return listFiles(basepath, func(path string) bool {
+		return filepath.Ext(path) == ".md"
+	})
+}
+
+// ListVaultFiles lists notes and every other file in the vault, so that links to attachments can be resolved
+func ListVaultFiles(basepath string) ([]string, error) {
+	return listFiles(basepath, func(path string) bool {
+		return true
+	})
+}
+
+func listFiles(basepath string, match func(path string) bool) ([]string, error) {
 	filelist := make([]string, 0)
 
 	err := filepath.Walk(basepath, func(path string, f os.FileInfo, err error) error {
@@ -105,15 +284,13 @@ func ListMdFiles(basepath string) ([]string, error) {
 		}
 
 		if f.IsDir() {
-			switch f.Name() {
-			case ".git", ".obsidian", ".trash":
+			if skippedDir(f.Name()) {
 				return filepath.SkipDir
-			default:
-				return nil
 			}
+			return nil
 		}
 
-		if filepath.Ext(path) != ".md" {
+		if !match(path) {
 			return nil
 		}
 
@@ -125,6 +302,17 @@ func ListMdFiles(basepath string) ([]string, error) {
 	return filelist, err
 }
 
+// skippedDir reports whether a directory is left out of every listing of the vault:
+// the Git and Obsidian metadata, the trash and the backups of olconv
+func skippedDir(name string) bool {
+	switch name {
+	case ".git", ".obsidian", ".trash", ".olconv":
+		return true
+	default:
+		return false
+	}
+}
+
 func FileListToMap(filelist []string) map[string][]string {
 	filemap := make(map[string][]string)
 	for _, path := range filelist {
@@ -138,16 +326,7 @@ func FileListToMap(filelist []string) map[string][]string {
 	return filemap
 }
 
+// filenameWithoutMdExtension returns the name of a file as written in a wikilink, without the .md extension of a note
 func filenameWithoutMdExtension(fullpath string) string {
-	filename := filepath.Base(fullpath)
-	filename = strings.ReplaceAll(filename, ".md", "")
-	filename, _ = url.QueryUnescape(filename)
-
-	return filename
-}
-func formatRelativePath(relativePath string) string {
-	relativePath = strings.TrimPrefix(relativePath, "./")
-	relativePath = strings.TrimSuffix(relativePath, ".md")
-	relativePath, _ = url.QueryUnescape(relativePath)
-	return relativePath
+	return strings.TrimSuffix(filepath.Base(fullpath), ".md")
 }
@@ -0,0 +1,165 @@
+package olconv
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FrontmatterMode decides how links in the YAML frontmatter of a note are handled
+type FrontmatterMode int
+
+const (
+	// SkipFrontmatter leaves the frontmatter untouched
+	SkipFrontmatter FrontmatterMode = iota
+	// ConvertFrontmatter converts links in property values and re-quotes them so that the YAML stays valid
+	ConvertFrontmatter
+)
+
+// ParseFrontmatterMode converts a flag value into a FrontmatterMode
+func ParseFrontmatterMode(s string) (FrontmatterMode, bool) {
+	switch s {
+	case "skip":
+		return SkipFrontmatter, true
+	case "convert":
+		return ConvertFrontmatter, true
+	default:
+		return SkipFrontmatter, false
+	}
+}
+
+// frontmatterEnd returns the index of the line closing the frontmatter, or -1 when the note has none.
+// The frontmatter must start on the first line with --- and is closed by --- or ...
+func frontmatterEnd(lines []string) int {
+	if len(lines) == 0 || strings.TrimRight(lines[0], " \t") != "---" {
+		return -1
+	}
+	for i := 1; i < len(lines); i++ {
+		switch strings.TrimRight(lines[i], " \t") {
+		case "---", "...":
+			return i
+		}
+	}
+	return -1
+}
+
+// frontmatterLinePattern splits a frontmatter line into its indentation, list markers and key, and its value
+var frontmatterLinePattern = regexp.MustCompile(`^(\s*(?:-\s+)*(?:[^\s"'#\-][^:]*:(?:\s+|$))?)(.*)$`)
+
+// convertFrontmatter converts the links in the property values of the frontmatter lines in place.
+// Values inside block scalars and flow collections are left untouched.
+func (c *Converter) convertFrontmatter(lines []string, direction LinkDirection) {
+	blockScalarIndent := -1
+	for i, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if blockScalarIndent != -1 {
+			if strings.TrimSpace(line) == "" || indent > blockScalarIndent {
+				continue
+			}
+			blockScalarIndent = -1
+		}
+
+		m := frontmatterLinePattern.FindStringSubmatch(line)
+		prefix, value := m[1], m[2]
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			blockScalarIndent = indent
+			continue
+		}
+
+		if converted, ok := c.convertYAMLScalar(value, direction); ok {
+			lines[i] = prefix + converted
+		}
+	}
+}
+
+// convertYAMLScalar converts the links in a single-line YAML scalar, keeping a trailing comment.
+// The result is quoted in the original style, or double-quoted when a plain scalar would no longer be valid.
+func (c *Converter) convertYAMLScalar(value string, direction LinkDirection) (string, bool) {
+	if value == "" {
+		return "", false
+	}
+
+	var text, rest string
+	style := value[0]
+	switch style {
+	case '"':
+		end := closingDoubleQuote(value)
+		if end == -1 {
+			return "", false
+		}
+		text, rest = value[1:end], value[end+1:]
+		// only \" and \\ are decoded, values with any other escape sequence are left untouched
+		if strings.Contains(strings.NewReplacer(`\\`, "", `\"`, "").Replace(text), `\`) {
+			return "", false
+		}
+		text = strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(text)
+	case '\'':
+		end := closingSingleQuote(value)
+		if end == -1 {
+			return "", false
+		}
+		text, rest = strings.ReplaceAll(value[1:end], "''", "'"), value[end+1:]
+	case '[', '{':
+		return "", false
+	default:
+		text = value
+		if i := strings.Index(value, " #"); i != -1 {
+			text, rest = value[:i], value[i:]
+		}
+	}
+
+	var converted string
+	switch direction {
+	case ToWikilink:
+		converted = c.convertMdToWikilink(text)
+	case ToMarkdown:
+		converted = c.convertWikilinkToMd(text)
+	default:
+		return "", false
+	}
+	if converted == text {
+		return "", false
+	}
+
+	switch {
+	case style == '\'':
+		return "'" + strings.ReplaceAll(converted, "'", "''") + "'" + rest, true
+	case style == '"' || needsQuoting(converted):
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(converted) + `"` + rest, true
+	default:
+		return converted + rest, true
+	}
+}
+
+// needsQuoting reports whether a string cannot be written as a plain YAML scalar
+func needsQuoting(s string) bool {
+	if s == "" || strings.ContainsAny(s[:1], "[]{},&*!|>'\"%@`#-?:") {
+		return true
+	}
+	return strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":")
+}
+
+func closingDoubleQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func closingSingleQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		if value[i] != '\'' {
+			continue
+		}
+		if i+1 < len(value) && value[i+1] == '\'' {
+			i++
+			continue
+		}
+		return i
+	}
+	return -1
+}
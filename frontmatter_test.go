@@ -0,0 +1,96 @@
+package olconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert_Frontmatter(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      FrontmatterMode
+		direction LinkDirection
+		input     string
+		want      string
+	}{
+		{
+			name:      "skip to markdown",
+			mode:      SkipFrontmatter,
+			direction: ToMarkdown,
+			input:     "---\nrelated: \"[[Other]]\"\n---\n[[Other]]\n",
+			want:      "---\nrelated: \"[[Other]]\"\n---\n[Other](Other.md)\n",
+		},
+		{
+			name:      "skip to wikilink",
+			mode:      SkipFrontmatter,
+			direction: ToWikilink,
+			input:     "---\nrelated: \"[Other](Other.md)\"\n---\n[Other](Other.md)\n",
+			want:      "---\nrelated: \"[Other](Other.md)\"\n---\n[[Other]]\n",
+		},
+		{
+			name:      "convert to markdown",
+			mode:      ConvertFrontmatter,
+			direction: ToMarkdown,
+			input: "---\n" +
+				"related: \"[[Other]]\"\n" +
+				"single: '[[Other|it''s]]'\n" +
+				"list:\n" +
+				"  - \"[[Other]]\" # comment\n" +
+				"  - \"[[Other|say \\\"hi\\\"]]\"\n" +
+				"escaped: \"[[Other]]\\n\"\n" +
+				"flow: [[Other]]\n" +
+				"block: |\n" +
+				"  [[Other]]\n" +
+				"---\n",
+			want: "---\n" +
+				"related: \"[Other](Other.md)\"\n" +
+				"single: '[it''s](Other.md)'\n" +
+				"list:\n" +
+				"  - \"[Other](Other.md)\" # comment\n" +
+				"  - \"[say \\\"hi\\\"](Other.md)\"\n" +
+				"escaped: \"[[Other]]\\n\"\n" +
+				"flow: [[Other]]\n" +
+				"block: |\n" +
+				"  [[Other]]\n" +
+				"---\n",
+		},
+		{
+			name:      "convert to wikilink",
+			mode:      ConvertFrontmatter,
+			direction: ToWikilink,
+			input: "---\n" +
+				"related: \"[Other](Other.md)\"\n" +
+				"sentence: see [Other](Other.md) # comment\n" +
+				"---\n",
+			want: "---\n" +
+				"related: \"[[Other]]\"\n" +
+				"sentence: see [[Other]] # comment\n" +
+				"---\n",
+		},
+		{
+			name:      "unclosed frontmatter is body text",
+			mode:      SkipFrontmatter,
+			direction: ToMarkdown,
+			input:     "---\n[[Other]]\n",
+			want:      "---\n[Other](Other.md)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConverter(".", map[string][]string{
+				"Other": {"Other.md"},
+			})
+			c.FrontmatterMode = tt.mode
+
+			w := &bytes.Buffer{}
+			err := c.Convert(strings.NewReader(tt.input), w, "note.md", tt.direction)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, w.String())
+		})
+	}
+}
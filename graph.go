@@ -0,0 +1,214 @@
+package olconv
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Graph is the note-to-note link graph of a vault
+type Graph struct {
+	// Nodes are the paths of every note from the vault root
+	Nodes []string `json:"nodes"`
+	Edges []Edge   `json:"edges"`
+}
+
+// Edge is a link from one note to another
+type Edge struct {
+	// Source and Target are the paths of the notes from the vault root
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Kind   LinkKind `json:"kind"`
+	// Text is the text displayed for the link
+	Text string `json:"text"`
+	// Anchor is the heading or block reference (^blockid) the link points to, if any
+	Anchor string `json:"anchor,omitempty"`
+	Embed  bool   `json:"embed"`
+	// Line is the 1-based line of the link in the source note
+	Line int `json:"line"`
+}
+
+// BuildGraph collects the links between the notes of the vault.
+// Links to attachments, links that cannot be resolved and links within a note are left out.
+// Notes listed in .olconvignore are not part of the graph, nor are the links to them.
+func BuildGraph(basepath string) (*Graph, error) {
+	files, c, err := newVaultConverter(basepath, Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Graph{
+		Nodes: make([]string, 0, len(files)),
+		Edges: make([]Edge, 0),
+	}
+	for _, file := range files {
+		g.Nodes = append(g.Nodes, c.vaultPath(file))
+
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		edges, err := c.edges(f, file)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		g.Edges = append(g.Edges, edges...)
+	}
+	return g, nil
+}
+
+// edges returns the links of the note read from r that point to other notes of the vault, in the order they appear
+func (c *Converter) edges(r io.Reader, path string) ([]Edge, error) {
+	links, err := ExtractLinks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c = c.forNote(path)
+
+	edges := make([]Edge, 0)
+	for _, link := range links {
+		file, ok := c.linkedFile(link)
+		if !ok || isAttachment(file) {
+			continue
+		}
+		anchor := link.Fragment
+		if link.Kind == MarkdownLinkKind && anchor != "" {
+			anchor = c.headingFromAnchor(file, anchor)
+		}
+		edges = append(edges, Edge{
+			Source: c.vaultPath(path),
+			Target: c.vaultPath(file),
+			Kind:   link.Kind,
+			Text:   link.Text,
+			Anchor: anchor,
+			Embed:  link.Embed,
+			Line:   link.Line,
+		})
+	}
+	return edges, nil
+}
+
+// WriteJSON writes the graph as a JSON object with nodes and edges
+func (g *Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, with the edge metadata as attributes
+func (g *Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph vault {\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(bw, "  %s;\n", dotQuote(node))
+	}
+	for _, e := range g.Edges {
+		attrs := []string{
+			"kind=" + dotQuote(string(e.Kind)),
+			"label=" + dotQuote(e.Text),
+		}
+		if e.Anchor != "" {
+			attrs = append(attrs, "anchor="+dotQuote(e.Anchor))
+		}
+		attrs = append(attrs, "embed="+strconv.FormatBool(e.Embed), "line="+strconv.Itoa(e.Line))
+		fmt.Fprintf(bw, "  %s -> %s [%s];\n", dotQuote(e.Source), dotQuote(e.Target), strings.Join(attrs, ", "))
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// dotQuote writes s as a DOT quoted string
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID string `xml:"id,attr"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph as GraphML, with the edge metadata as data keys
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "kind", For: "edge", Name: "kind", Type: "string"},
+			{ID: "text", For: "edge", Name: "text", Type: "string"},
+			{ID: "anchor", For: "edge", Name: "anchor", Type: "string"},
+			{ID: "embed", For: "edge", Name: "embed", Type: "boolean"},
+			{ID: "line", For: "edge", Name: "line", Type: "int"},
+		},
+		Graph: graphMLGraph{
+			ID:          "vault",
+			EdgeDefault: "directed",
+			Nodes:       make([]graphMLNode, 0, len(g.Nodes)),
+			Edges:       make([]graphMLEdge, 0, len(g.Edges)),
+		},
+	}
+	for _, node := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: node})
+	}
+	for _, e := range g.Edges {
+		data := []graphMLData{
+			{Key: "kind", Value: string(e.Kind)},
+			{Key: "text", Value: e.Text},
+		}
+		if e.Anchor != "" {
+			data = append(data, graphMLData{Key: "anchor", Value: e.Anchor})
+		}
+		data = append(data,
+			graphMLData{Key: "embed", Value: strconv.FormatBool(e.Embed)},
+			graphMLData{Key: "line", Value: strconv.Itoa(e.Line)},
+		)
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: e.Source, Target: e.Target, Data: data})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,86 @@
+package olconv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildGraph(t *testing.T) {
+	dir := writeVault(t, map[string]string{
+		"index.md": "---\nup: [[a]]\n---\n" +
+			"[A](a.md#Setup%20steps) [[sub/b#^block|B]] ![[a]] [[missing]] [[#Local]] ![[img.png]]\n" +
+			"`[[a]]` [site](https://example.com)\n",
+		"a.md":     "# A\n\n## Setup steps\n\n[[index]]\n",
+		"sub/b.md": "no links\n",
+		"img.png":  "",
+	})
+
+	g, err := BuildGraph(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a.md", "index.md", "sub/b.md"}, g.Nodes)
+	assert.Equal(t, []Edge{
+		{Source: "a.md", Target: "index.md", Kind: WikilinkKind, Text: "index", Line: 5},
+		{Source: "index.md", Target: "a.md", Kind: MarkdownLinkKind, Text: "A", Anchor: "Setup steps", Line: 4},
+		{Source: "index.md", Target: "sub/b.md", Kind: WikilinkKind, Text: "B", Anchor: "^block", Line: 4},
+		{Source: "index.md", Target: "a.md", Kind: WikilinkKind, Text: "a", Embed: true, Line: 4},
+	}, g.Edges)
+}
+
+func TestGraph_Write(t *testing.T) {
+	g := &Graph{
+		Nodes: []string{"a.md", `say "hi".md`},
+		Edges: []Edge{
+			{Source: "a.md", Target: `say "hi".md`, Kind: WikilinkKind, Text: "hi & bye", Anchor: "Top", Line: 3},
+			{Source: `say "hi".md`, Target: "a.md", Kind: MarkdownLinkKind, Text: "a", Embed: true, Line: 1},
+		},
+	}
+
+	dot := &strings.Builder{}
+	require.NoError(t, g.WriteDOT(dot))
+	assert.Equal(t, `digraph vault {
+  "a.md";
+  "say \"hi\".md";
+  "a.md" -> "say \"hi\".md" [kind="wikilink", label="hi & bye", anchor="Top", embed=false, line=3];
+  "say \"hi\".md" -> "a.md" [kind="markdown", label="a", embed=true, line=1];
+}
+`, dot.String())
+
+	graphml := &strings.Builder{}
+	require.NoError(t, g.WriteGraphML(graphml))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="kind" for="edge" attr.name="kind" attr.type="string"></key>
+  <key id="text" for="edge" attr.name="text" attr.type="string"></key>
+  <key id="anchor" for="edge" attr.name="anchor" attr.type="string"></key>
+  <key id="embed" for="edge" attr.name="embed" attr.type="boolean"></key>
+  <key id="line" for="edge" attr.name="line" attr.type="int"></key>
+  <graph id="vault" edgedefault="directed">
+    <node id="a.md"></node>
+    <node id="say &#34;hi&#34;.md"></node>
+    <edge source="a.md" target="say &#34;hi&#34;.md">
+      <data key="kind">wikilink</data>
+      <data key="text">hi &amp; bye</data>
+      <data key="anchor">Top</data>
+      <data key="embed">false</data>
+      <data key="line">3</data>
+    </edge>
+    <edge source="say &#34;hi&#34;.md" target="a.md">
+      <data key="kind">markdown</data>
+      <data key="text">a</data>
+      <data key="embed">true</data>
+      <data key="line">1</data>
+    </edge>
+  </graph>
+</graphml>
+`, graphml.String())
+
+	js := &strings.Builder{}
+	require.NoError(t, g.WriteJSON(js))
+	assert.Contains(t, js.String(), `"source": "a.md",`)
+	assert.Contains(t, js.String(), `"anchor": "Top",`)
+	assert.Contains(t, js.String(), `"embed": true,`)
+}
@@ -0,0 +1,328 @@
+package olconv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is the name of the files listing, with the syntax of .gitignore, the files of a vault olconv leaves alone
+const IgnoreFile = ".olconvignore"
+
+// vaultFilter selects the files of a vault that are converted and the files links are resolved against
+type vaultFilter struct {
+	basepath      string
+	include       []string
+	exclude       []string
+	gitignore     bool
+	indexExcluded bool
+	// rules are read from the ignore files of the directories visited by list
+	rules []ignoreRule
+}
+
+// newVaultFilter returns the filter of the vault at basepath configured by opts, failing on malformed globs
+func newVaultFilter(basepath string, opts Options) (*vaultFilter, error) {
+	f := &vaultFilter{
+		basepath:      basepath,
+		gitignore:     opts.GitIgnore,
+		indexExcluded: opts.IndexExcluded,
+	}
+	for _, globs := range []struct {
+		dst *[]string
+		src []string
+	}{{&f.include, opts.Include}, {&f.exclude, opts.Exclude}} {
+		for _, glob := range globs.src {
+			glob = strings.TrimPrefix(strings.TrimPrefix(filepath.ToSlash(glob), "./"), "/")
+			if !validGlob(glob) {
+				return nil, fmt.Errorf("invalid glob %q: %w", glob, path.ErrBadPattern)
+			}
+			*globs.dst = append(*globs.dst, glob)
+		}
+	}
+	return f, nil
+}
+
+// list returns the notes to convert and the files links are resolved against.
+// Excluded files are only listed in the latter with indexExcluded.
+func (f *vaultFilter) list() ([]string, []string, error) {
+	notes := make([]string, 0)
+	files := make([]string, 0)
+
+	err := filepath.Walk(f.basepath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := vaultRelPath(f.basepath, p)
+
+		if info.IsDir() {
+			if skippedDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			if rel == "." {
+				return f.load(p, "")
+			}
+			if f.excluded(rel) {
+				if !f.indexExcluded {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			return f.load(p, rel)
+		}
+
+		if f.excluded(rel) {
+			if f.indexExcluded {
+				files = append(files, p)
+			}
+			return nil
+		}
+		files = append(files, p)
+		if filepath.Ext(p) == ".md" && f.included(rel) {
+			notes = append(notes, p)
+		}
+		return nil
+	})
+
+	return notes, files, err
+}
+
+// selectFiles returns the given files as listed in the vault, leaving out the excluded ones.
+// It must be called after list, which reads the ignore files.
+func (f *vaultFilter) selectFiles(names []string) ([]string, error) {
+	files := make([]string, 0, len(names))
+	for _, name := range names {
+		file, err := vaultFile(f.basepath, name)
+		if err != nil {
+			return nil, err
+		}
+		if f.excluded(vaultRelPath(f.basepath, file)) {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// load reads the ignore files of the directory dir, whose path from the vault root is base
+func (f *vaultFilter) load(dir, base string) error {
+	names := []string{IgnoreFile}
+	if f.gitignore {
+		names = append(names, ".gitignore")
+	}
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		f.rules = append(f.rules, parseIgnore(base, string(content))...)
+	}
+	return nil
+}
+
+// excluded reports whether the file at rel, a path from the vault root, or one of its directories
+// is ignored or matches an exclude glob
+func (f *vaultFilter) excluded(rel string) bool {
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		name := strings.Join(parts[:i+1], "/")
+		dir := i < len(parts)-1
+		if f.ignored(name, dir) {
+			return true
+		}
+		for _, glob := range f.exclude {
+			if matchGlob(glob, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// included reports whether the note at rel, or one of its directories, matches an include glob.
+// Every note is included when there are no include globs.
+func (f *vaultFilter) included(rel string) bool {
+	if len(f.include) == 0 {
+		return true
+	}
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		name := strings.Join(parts[:i+1], "/")
+		for _, glob := range f.include {
+			if matchGlob(glob, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ignored reports whether the last ignore rule matching name excludes it
+func (f *vaultFilter) ignored(name string, dir bool) bool {
+	ignored := false
+	for _, rule := range f.rules {
+		if rule.match(name, dir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// ignoreRule is a pattern of an ignore file
+type ignoreRule struct {
+	// base is the directory of the ignore file from the vault root, empty at the root
+	base    string
+	pattern string
+	negate  bool
+	dirOnly bool
+	// anchored patterns contain a slash and match paths from base, the others match names at any depth
+	anchored bool
+}
+
+// parseIgnore reads the rules of an ignore file in the directory base, following the syntax of .gitignore
+func parseIgnore(base, content string) []ignoreRule {
+	rules := make([]ignoreRule, 0)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+			line = line[:len(line)-1]
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if line[0] == '!' {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" || !validPattern(line) {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// match reports whether the rule matches name, a path from the vault root
+func (r ignoreRule) match(name string, dir bool) bool {
+	if r.dirOnly && !dir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(name, r.base+"/") {
+			return false
+		}
+		name = name[len(r.base)+1:]
+	}
+	if r.anchored {
+		return matchPattern(r.pattern, name)
+	}
+	return matchPattern(r.pattern, path.Base(name))
+}
+
+// matchGlob reports whether the slash-separated name matches the glob.
+// Besides the syntax of path.Match, ** matches any number of directories and {a,b} matches either alternative.
+func matchGlob(glob, name string) bool {
+	for _, pattern := range expandBraces(glob) {
+		if matchPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// validGlob reports whether every alternative of the glob is well-formed
+func validGlob(glob string) bool {
+	for _, pattern := range expandBraces(glob) {
+		if !validPattern(pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPattern matches name against a pattern that may contain ** but no braces
+func matchPattern(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func validPattern(pattern string) bool {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// expandBraces returns the alternatives of the first {a,b} group of the glob, expanded recursively.
+// Unbalanced braces are kept as is.
+func expandBraces(glob string) []string {
+	start := strings.IndexByte(glob, '{')
+	if start == -1 {
+		return []string{glob}
+	}
+
+	depth := 0
+	alternatives := make([]string, 0)
+	last := start + 1
+	for i := start; i < len(glob); i++ {
+		switch glob[i] {
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, glob[last:i])
+				last = i + 1
+			}
+		case '}':
+			depth--
+			if depth == 0 {
+				alternatives = append(alternatives, glob[last:i])
+				expanded := make([]string, 0, len(alternatives))
+				for _, alternative := range alternatives {
+					expanded = append(expanded, expandBraces(glob[:start]+alternative+glob[i+1:])...)
+				}
+				return expanded
+			}
+		}
+	}
+	return []string{glob}
+}
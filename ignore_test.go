@@ -0,0 +1,167 @@
+package olconv
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob string
+		name string
+		want bool
+	}{
+		{"*.md", "note.md", true},
+		{"*.md", "sub/note.md", false},
+		{"**/*.md", "note.md", true},
+		{"**/*.md", "a/b/note.md", true},
+		{"notes/**", "notes/a/b.md", true},
+		{"notes/**", "other/b.md", false},
+		{"a/**/b.md", "a/b.md", true},
+		{"a/**/b.md", "a/x/y/b.md", true},
+		{"{templates,_archive}/**", "_archive/old.md", true},
+		{"{templates,_archive}/**", "notes/old.md", false},
+		{"**/*.{png,jpg}", "img/photo.jpg", true},
+		{"draft-?.md", "draft-1.md", true},
+		{"[ab].md", "c.md", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchGlob(tt.glob, tt.name))
+		})
+	}
+}
+
+func TestParseIgnore(t *testing.T) {
+	rules := parseIgnore("sub", "# comment\n\ntemplates/\n/root.md\n*.tmp  \n!keep.tmp\n\\#hash.md\n")
+	assert.Equal(t, []ignoreRule{
+		{base: "sub", pattern: "templates", dirOnly: true},
+		{base: "sub", pattern: "root.md", anchored: true},
+		{base: "sub", pattern: "*.tmp"},
+		{base: "sub", pattern: "keep.tmp", negate: true},
+		{base: "sub", pattern: "#hash.md"},
+	}, rules)
+
+	f := &vaultFilter{rules: rules}
+	assert.True(t, f.excluded("sub/templates/daily.md"))
+	assert.False(t, f.excluded("templates/daily.md"))
+	assert.False(t, f.excluded("sub/a/templates"))
+	assert.True(t, f.excluded("sub/root.md"))
+	assert.False(t, f.excluded("sub/a/root.md"))
+	assert.True(t, f.excluded("sub/a/x.tmp"))
+	assert.False(t, f.excluded("sub/a/keep.tmp"))
+	assert.True(t, f.excluded("sub/#hash.md"))
+}
+
+func TestVaultFilter_List(t *testing.T) {
+	dir := writeVault(t, map[string]string{
+		".olconvignore":        "_archive/\n",
+		".gitignore":           "build/\n",
+		"index.md":             "",
+		"notes/a.md":           "",
+		"notes/.olconvignore":  "*.draft.md\n",
+		"notes/b.draft.md":     "",
+		"notes/image.png":      "",
+		"templates/daily.md":   "",
+		"_archive/old.md":      "",
+		"build/out.md":         "",
+		".obsidian/app.json":   "{}",
+		"other/.olconvignore":  "/index.md\n",
+		"other/index.md":       "",
+		"other/sub/index.md":   "",
+		"vendor/docs/guide.md": "",
+	})
+	rel := func(files []string) []string {
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			names = append(names, vaultRelPath(dir, file))
+		}
+		return names
+	}
+
+	tests := []struct {
+		name      string
+		opts      Options
+		wantNotes []string
+		wantFiles []string
+	}{
+		{
+			name:      "ignore files",
+			opts:      Options{},
+			wantNotes: []string{"build/out.md", "index.md", "notes/a.md", "other/sub/index.md", "templates/daily.md", "vendor/docs/guide.md"},
+			wantFiles: []string{".gitignore", ".olconvignore", "build/out.md", "index.md", "notes/.olconvignore", "notes/a.md", "notes/image.png", "other/.olconvignore", "other/sub/index.md", "templates/daily.md", "vendor/docs/guide.md"},
+		},
+		{
+			name:      "exclude and gitignore",
+			opts:      Options{Exclude: []string{"templates", "vendor/**"}, GitIgnore: true},
+			wantNotes: []string{"index.md", "notes/a.md", "other/sub/index.md"},
+			wantFiles: []string{".gitignore", ".olconvignore", "index.md", "notes/.olconvignore", "notes/a.md", "notes/image.png", "other/.olconvignore", "other/sub/index.md"},
+		},
+		{
+			name:      "include",
+			opts:      Options{Include: []string{"notes", "**/index.md"}},
+			wantNotes: []string{"index.md", "notes/a.md", "other/sub/index.md"},
+			wantFiles: []string{".gitignore", ".olconvignore", "build/out.md", "index.md", "notes/.olconvignore", "notes/a.md", "notes/image.png", "other/.olconvignore", "other/sub/index.md", "templates/daily.md", "vendor/docs/guide.md"},
+		},
+		{
+			name:      "index excluded",
+			opts:      Options{Exclude: []string{"templates/**"}, IndexExcluded: true},
+			wantNotes: []string{"build/out.md", "index.md", "notes/a.md", "other/sub/index.md", "vendor/docs/guide.md"},
+			wantFiles: []string{".gitignore", ".olconvignore", "_archive/old.md", "build/out.md", "index.md", "notes/.olconvignore", "notes/a.md", "notes/b.draft.md", "notes/image.png", "other/.olconvignore", "other/index.md", "other/sub/index.md", "templates/daily.md", "vendor/docs/guide.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newVaultFilter(dir, tt.opts)
+			require.NoError(t, err)
+			notes, files, err := f.list()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNotes, rel(notes))
+			assert.Equal(t, tt.wantFiles, rel(files))
+		})
+	}
+
+	_, err := newVaultFilter(dir, Options{Exclude: []string{"notes/[a"}})
+	assert.Error(t, err)
+}
+
+func TestConvertVault_Exclude(t *testing.T) {
+	files := map[string]string{
+		".olconvignore":      "_archive/\n",
+		"index.md":           "[Daily](templates/daily.md) [Old](_archive/old.md)\n",
+		"templates/daily.md": "[Index](../index.md)\n",
+		"_archive/old.md":    "[Index](../index.md)\n",
+	}
+
+	dir := writeVault(t, files)
+	_, err := ConvertVault(context.Background(), dir, Options{Direction: ToWikilink, Exclude: []string{"templates/**"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		// links to excluded notes cannot be resolved, and are written with their path
+		"index.md":           "[[templates/daily|Daily]] [[_archive/old|Old]]\n",
+		"templates/daily.md": "[Index](../index.md)\n",
+		"_archive/old.md":    "[Index](../index.md)\n",
+	}, readVault(t, dir, "index.md", "templates/daily.md", "_archive/old.md"))
+
+	dir = writeVault(t, files)
+	_, err = ConvertVault(context.Background(), dir, Options{Direction: ToWikilink, Exclude: []string{"templates/**"}, IndexExcluded: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"index.md":           "[[daily|Daily]] [[old|Old]]\n",
+		"templates/daily.md": "[Index](../index.md)\n",
+		"_archive/old.md":    "[Index](../index.md)\n",
+	}, readVault(t, dir, "index.md", "templates/daily.md", "_archive/old.md"))
+
+	// excluded notes are skipped even when named
+	dir = writeVault(t, files)
+	summary, err := ConvertVault(context.Background(), dir, Options{
+		Direction: ToWikilink,
+		Files:     []string{filepath.Join(dir, "_archive", "old.md")},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, summary.Files)
+}
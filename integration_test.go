@@ -1,10 +1,13 @@
 package olconv
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,9 +20,19 @@ func TestConvertUnderDir_Integration(t *testing.T) {
 	// Copy test files to temp directory
 	copyTestVault(t, "testdata/sample_vault", tempDir)
 
+	// wikilinks.md has no Markdown links to convert
+	unchangedFile := filepath.Join(tempDir, "wikilinks.md")
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(unchangedFile, modTime, modTime))
+
 	// Run conversion
-	err := LinkToWikilink(tempDir)
+	_, err := ConvertVault(context.Background(), tempDir, Options{Direction: ToWikilink})
+	require.NoError(t, err)
+
+	// Verify files without changes are not rewritten
+	unchangedInfo, err := os.Stat(unchangedFile)
 	require.NoError(t, err)
+	assert.True(t, unchangedInfo.ModTime().Equal(modTime))
 
 	// Verify index.md conversion
 	indexContent, err := os.ReadFile(filepath.Join(tempDir, "index.md"))
@@ -52,6 +65,19 @@ func TestConvertUnderDir_Integration(t *testing.T) {
 
 	assert.Contains(t, basicStr, "[[index]]")
 	assert.Contains(t, basicStr, "[[note with spaces]]")
+
+	// Verify destinations are resolved relative to the note
+	sub1Content, err := os.ReadFile(filepath.Join(tempDir, "sub1", "samename.md"))
+	require.NoError(t, err)
+	sub1Str := string(sub1Content)
+
+	assert.Contains(t, sub1Str, "[[index|Back to index]]")
+	assert.Contains(t, sub1Str, "[[sub2/samename|Other samename]]")
+
+	// Verify embeds are converted
+	embedsContent, err := os.ReadFile(filepath.Join(tempDir, "embeds.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(embedsContent), "![[diagram.png|Diagram]]")
 }
 
 func TestReverseConvertUnderDir_Integration(t *testing.T) {
@@ -62,7 +88,7 @@ func TestReverseConvertUnderDir_Integration(t *testing.T) {
 	copyTestVault(t, "testdata/sample_vault", tempDir)
 
 	// Run reverse conversion on wikilinks file
-	err := WikilinkToLink(tempDir)
+	_, err := ConvertVault(context.Background(), tempDir, Options{Direction: ToMarkdown})
 	require.NoError(t, err)
 
 	// Verify wikilinks.md conversion
@@ -72,7 +98,7 @@ func TestReverseConvertUnderDir_Integration(t *testing.T) {
 
 	// Check that wikilinks are converted to markdown links
 	assert.Contains(t, wikilinksStr, "[basic](basic.md)")
-	assert.Contains(t, wikilinksStr, "[note with spaces](note with spaces.md)")
+	assert.Contains(t, wikilinksStr, "[note with spaces](note%20with%20spaces.md)")
 	assert.Contains(t, wikilinksStr, "[Basic Note Link](basic.md)")
 	assert.Contains(t, wikilinksStr, "[First Same Name](sub1/samename.md)")
 	assert.Contains(t, wikilinksStr, "[Second Same Name](sub2/samename.md)")
@@ -86,6 +112,15 @@ func TestReverseConvertUnderDir_Integration(t *testing.T) {
 
 	// Check that code span wikilinks are NOT converted
 	assert.Contains(t, wikilinksStr, "`[[inline-code]]`")
+
+	// Verify embeds keep attachment extensions
+	embedsContent, err := os.ReadFile(filepath.Join(tempDir, "embeds.md"))
+	require.NoError(t, err)
+	embedsStr := string(embedsContent)
+
+	assert.Contains(t, embedsStr, "![diagram.png](attachments/diagram.png)")
+	assert.Contains(t, embedsStr, "![diagram.png|300](attachments/diagram.png)")
+	assert.Contains(t, embedsStr, "![basic](basic.md)")
 }
 
 func TestFileMapping_Integration(t *testing.T) {
@@ -101,6 +136,12 @@ func TestFileMapping_Integration(t *testing.T) {
 	assert.Contains(t, samenames, "testdata/sample_vault/sub1/samename.md")
 	assert.Contains(t, samenames, "testdata/sample_vault/sub2/samename.md")
 
+	// Check attachments are indexed with their extension
+	vaultFiles, err := ListVaultFiles("testdata/sample_vault")
+	require.NoError(t, err)
+	assert.Contains(t, FileListToMap(vaultFiles)["diagram.png"], "testdata/sample_vault/attachments/diagram.png")
+	assert.NotContains(t, files, "testdata/sample_vault/attachments/diagram.png")
+
 	// Check unique files
 	basics := filemap["basic"]
 	assert.Len(t, basics, 1)
@@ -122,13 +163,15 @@ func TestRoundTripConversion_Integration(t *testing.T) {
 	// Read original content
 	originalContent, err := os.ReadFile(filepath.Join(tempDir, "index.md"))
 	require.NoError(t, err)
+	originalSub1Content, err := os.ReadFile(filepath.Join(tempDir, "sub1", "samename.md"))
+	require.NoError(t, err)
 
 	// Convert markdown links to wikilinks
-	err = LinkToWikilink(tempDir)
+	_, err = ConvertVault(context.Background(), tempDir, Options{Direction: ToWikilink})
 	require.NoError(t, err)
 
 	// Convert wikilinks back to markdown links
-	err = WikilinkToLink(tempDir)
+	_, err = ConvertVault(context.Background(), tempDir, Options{Direction: ToMarkdown})
 	require.NoError(t, err)
 
 	// Read final content
@@ -146,6 +189,60 @@ func TestRoundTripConversion_Integration(t *testing.T) {
 
 	// Should have same number of markdown links
 	assert.Equal(t, originalMdLinks, finalMdLinks)
+
+	// Relative links in subdirectories should be restored as they were
+	finalSub1Content, err := os.ReadFile(filepath.Join(tempDir, "sub1", "samename.md"))
+	require.NoError(t, err)
+	assert.Equal(t, string(originalSub1Content), string(finalSub1Content))
+}
+
+func TestRoundTripPathStyles_Integration(t *testing.T) {
+	note := "[[basic]] [[special]] [[other]]\n"
+	for _, name := range []string{"default", "shortest", "absolute", "relative"} {
+		style, _ := ParsePathStyle(name)
+		t.Run(name, func(t *testing.T) {
+			dir := writeVault(t, map[string]string{
+				"basic.md":        "# Basic\n",
+				"sub1/special.md": "# Special\n",
+				"sub1/a.md":       note,
+				"sub2/other.md":   "# Other\n",
+			})
+
+			_, err := ConvertVault(context.Background(), dir, Options{Direction: ToMarkdown, PathStyle: style})
+			require.NoError(t, err)
+
+			// the written links are found again, and converted back to the shortest wikilinks
+			problems, err := CheckVault(dir)
+			require.NoError(t, err)
+			assert.Empty(t, problems)
+
+			_, err = ConvertVault(context.Background(), dir, Options{Direction: ToWikilink})
+			require.NoError(t, err)
+			assert.Equal(t, note, readVault(t, dir, "sub1/a.md")["sub1/a.md"])
+		})
+	}
+}
+
+func TestRoundTripReservedCharacters_Integration(t *testing.T) {
+	note := "[[a+b]] [[100%]] [[note (draft]]\n"
+	dir := writeVault(t, map[string]string{
+		"index.md":           note,
+		"a+b.md":             "",
+		"100%.md":            "",
+		"sub/note (draft.md": "",
+	})
+
+	_, err := ConvertVault(context.Background(), dir, Options{Direction: ToMarkdown})
+	require.NoError(t, err)
+	assert.Equal(t, "[a+b](a+b.md) [100%](100%25.md) [note (draft](sub/note%20%28draft.md)\n", readVault(t, dir, "index.md")["index.md"])
+
+	problems, err := CheckVault(dir)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	_, err = ConvertVault(context.Background(), dir, Options{Direction: ToWikilink})
+	require.NoError(t, err)
+	assert.Equal(t, note, readVault(t, dir, "index.md")["index.md"])
 }
 
 func TestEdgeCases_Integration(t *testing.T) {
@@ -156,7 +253,7 @@ func TestEdgeCases_Integration(t *testing.T) {
 	copyTestVault(t, "testdata/sample_vault", tempDir)
 
 	// Run conversion
-	err := LinkToWikilink(tempDir)
+	_, err := ConvertVault(context.Background(), tempDir, Options{Direction: ToWikilink})
 	require.NoError(t, err)
 
 	// Verify edge_cases.md conversion
@@ -188,7 +285,7 @@ func TestJapaneseFiles_Integration(t *testing.T) {
 	copyTestVault(t, "testdata/sample_vault", tempDir)
 
 	// Run conversion
-	err := LinkToWikilink(tempDir)
+	_, err := ConvertVault(context.Background(), tempDir, Options{Direction: ToWikilink})
 	require.NoError(t, err)
 
 	// Verify Japanese file conversion
@@ -211,7 +308,7 @@ func TestEmptyFiles_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Run conversion (should not crash on empty files)
-	err = LinkToWikilink(tempDir)
+	_, err = ConvertVault(context.Background(), tempDir, Options{Direction: ToWikilink})
 	require.NoError(t, err)
 
 	// File should still be empty
@@ -220,6 +317,193 @@ func TestEmptyFiles_Integration(t *testing.T) {
 	assert.Empty(t, content)
 }
 
+func TestConvertVault_DryRun_Integration(t *testing.T) {
+	// Create temporary test directory
+	tempDir := t.TempDir()
+
+	// Copy test files to temp directory
+	copyTestVault(t, "testdata/sample_vault", tempDir)
+
+	originalContent, err := os.ReadFile(filepath.Join(tempDir, "basic.md"))
+	require.NoError(t, err)
+
+	// Run dry-run conversion
+	out := &strings.Builder{}
+	summary, err := ConvertVault(context.Background(), tempDir, Options{Direction: ToWikilink, DryRun: true, Diff: out})
+	require.NoError(t, err)
+
+	// Files should not be modified
+	finalContent, err := os.ReadFile(filepath.Join(tempDir, "basic.md"))
+	require.NoError(t, err)
+	assert.Equal(t, string(originalContent), string(finalContent))
+
+	// Diff and summary should describe the changes
+	basicPath := filepath.Join(tempDir, "basic.md")
+	assert.Contains(t, summary.Files, basicPath)
+	assert.NotContains(t, summary.Files, filepath.Join(tempDir, "wikilinks.md"))
+	assert.Greater(t, summary.Links, len(summary.Files))
+	assert.Contains(t, out.String(), "--- a/basic.md\n+++ b/basic.md\n")
+	assert.Contains(t, out.String(), "\n-- Back to [index](index.md)\n")
+	assert.Contains(t, out.String(), "\n+- Back to [[index]]\n")
+}
+
+func TestConvertVault_Integration(t *testing.T) {
+	// Create temporary test directory
+	tempDir := t.TempDir()
+
+	// Copy test files to temp directory
+	copyTestVault(t, "testdata/sample_vault", tempDir)
+
+	originalContent, err := os.ReadFile(filepath.Join(tempDir, "wikilinks.md"))
+	require.NoError(t, err)
+
+	// Dry run reports the changes without writing them
+	out := &strings.Builder{}
+	opts := Options{
+		Direction: ToMarkdown,
+		DryRun:    true,
+		Diff:      out,
+		Rewrite: func(ctx LinkContext) (string, bool) {
+			if ctx.Link.Target != "basic" {
+				return "", false
+			}
+			return "[Basic](https://example.com/basic)", true
+		},
+	}
+	summary, err := ConvertVault(context.Background(), tempDir, opts)
+	require.NoError(t, err)
+	assert.Contains(t, summary.Files, filepath.Join(tempDir, "wikilinks.md"))
+	assert.Contains(t, out.String(), "\n+- [Basic](https://example.com/basic)\n")
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "wikilinks.md"))
+	require.NoError(t, err)
+	assert.Equal(t, string(originalContent), string(content))
+
+	// Without dry run the same changes are written
+	opts.DryRun = false
+	opts.Diff = nil
+	written, err := ConvertVault(context.Background(), tempDir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, summary, written)
+
+	content, err = os.ReadFile(filepath.Join(tempDir, "wikilinks.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "- [Basic](https://example.com/basic)\n")
+	assert.Contains(t, string(content), "[First Same Name](sub1/samename.md)")
+
+	// A canceled context stops the conversion
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ConvertVault(ctx, tempDir, Options{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestConvertVault_Files_Integration(t *testing.T) {
+	// Create temporary test directory
+	tempDir := t.TempDir()
+
+	// Copy test files to temp directory
+	copyTestVault(t, "testdata/sample_vault", tempDir)
+
+	originalIndex, err := os.ReadFile(filepath.Join(tempDir, "index.md"))
+	require.NoError(t, err)
+
+	// Only the named notes are converted, with links resolved against the whole vault
+	summary, err := ConvertVault(context.Background(), tempDir, Options{
+		Files: []string{filepath.Join(tempDir, "sub1", "samename.md")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(tempDir, "sub1", "samename.md")}, summary.Files)
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "sub1", "samename.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "[[sub2/samename|Other samename]]")
+
+	content, err = os.ReadFile(filepath.Join(tempDir, "index.md"))
+	require.NoError(t, err)
+	assert.Equal(t, string(originalIndex), string(content))
+
+	// Notes outside the vault are rejected
+	_, err = ConvertVault(context.Background(), tempDir, Options{Files: []string{filepath.Join(tempDir, "..", "other.md")}})
+	assert.Error(t, err)
+}
+
+func TestConvertVault_Jobs_Integration(t *testing.T) {
+	// Create temporary test directory
+	tempDir := t.TempDir()
+
+	// Copy test files to temp directory
+	copyTestVault(t, "testdata/sample_vault", tempDir)
+
+	// Diffs and summaries are in the same order whatever the number of jobs
+	convert := func(jobs int) (Summary, string) {
+		out := &strings.Builder{}
+		summary, err := ConvertVault(context.Background(), tempDir, Options{Direction: ToMarkdown, DryRun: true, Diff: out, Jobs: jobs})
+		require.NoError(t, err)
+		return summary, out.String()
+	}
+	want, wantDiff := convert(1)
+	require.NotEmpty(t, want.Files)
+	for _, jobs := range []int{2, 8, 0} {
+		summary, diff := convert(jobs)
+		assert.Equal(t, want, summary, "jobs=%d", jobs)
+		assert.Equal(t, wantDiff, diff, "jobs=%d", jobs)
+	}
+
+	// The first error stops the conversion
+	failed := errors.New("failed")
+	_, err := ConvertVault(context.Background(), tempDir, Options{Direction: ToMarkdown, Diff: errWriter{failed}, Jobs: 4})
+	assert.ErrorIs(t, err, failed)
+}
+
+// errWriter fails every write with err
+type errWriter struct {
+	err error
+}
+
+func (w errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestConvertReader_Integration(t *testing.T) {
+	out := &strings.Builder{}
+	err := ConvertReader("testdata/sample_vault", "testdata/sample_vault/sub1/buffer.md", strings.NewReader("[[samename]] and [[special]]\n"), out, Options{Direction: ToMarkdown})
+	require.NoError(t, err)
+	assert.Equal(t, "[samename](samename.md) and [special](special.md)\n", out.String())
+
+	// without a path the note is at the vault root
+	out.Reset()
+	err = ConvertReader("testdata/sample_vault", "", strings.NewReader("[Special](sub1/special.md)"), out, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "[[special|Special]]", out.String())
+}
+
+func TestCheckVault_Integration(t *testing.T) {
+	// Create temporary test directory
+	tempDir := t.TempDir()
+
+	// Copy test files to temp directory
+	copyTestVault(t, "testdata/sample_vault", tempDir)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "ambiguous.md"), []byte("[[samename]] [[sub1/samename]]\n"), 0644))
+
+	problems, err := CheckVault(tempDir)
+	require.NoError(t, err)
+
+	reported := make([]string, 0, len(problems))
+	for _, p := range problems {
+		reported = append(reported, p.String())
+	}
+	assert.Contains(t, reported, `ambiguous.md:1:1: wikilink target "samename" is ambiguous, it matches sub1/samename.md, sub2/samename.md`)
+	assert.Contains(t, reported, `edge_cases.md:6:3: link target "paren file.md" not found`)
+	assert.Contains(t, reported, `edge_cases.md:7:3: wikilink target "paren file" not found`)
+
+	// links that resolve are not reported
+	for _, p := range problems {
+		assert.NotEqual(t, "index.md", p.File)
+		assert.NotEqual(t, "embeds.md", p.File)
+	}
+}
+
 // Helper function to copy test vault to temporary directory
 func copyTestVault(t *testing.T, src, dst string) {
 	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
@@ -0,0 +1,217 @@
+package olconv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JournalDir is the directory, relative to the vault root, where backups of conversions are stored
+const JournalDir = ".olconv/backup"
+
+// journalManifest lists the recorded notes of a run, one JSON object per line
+const journalManifest = "manifest.jsonl"
+
+// Journal records the original content of every note rewritten by a conversion, so that the run can be undone
+type Journal struct {
+	basepath string
+	dir      string
+	run      string
+	manifest *os.File
+	recorded int
+}
+
+type journalEntry struct {
+	Path      string `json:"path"`
+	Original  string `json:"original"`
+	Converted string `json:"converted"`
+}
+
+// NewJournal starts a new backup run in the journal directory of the vault
+func NewJournal(basepath string) (*Journal, error) {
+	run := time.Now().Format("20060102-150405.000")
+	dir := filepath.Join(basepath, filepath.FromSlash(JournalDir), run)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Journal{
+		basepath: basepath,
+		dir:      dir,
+		run:      run,
+	}, nil
+}
+
+// Run returns the name of the run, which can be passed to Undo
+func (j *Journal) Run() string {
+	return j.run
+}
+
+// Len returns the number of notes recorded in the run
+func (j *Journal) Len() int {
+	return j.recorded
+}
+
+// Record saves the original content of a note before it is replaced by converted
+func (j *Journal) Record(path string, original, converted []byte) error {
+	rel, err := filepath.Rel(j.basepath, path)
+	if err != nil {
+		return err
+	}
+
+	backup := filepath.Join(j.dir, rel)
+	if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(backup, original, 0644); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(journalEntry{
+		Path:      filepath.ToSlash(rel),
+		Original:  checksum(original),
+		Converted: checksum(converted),
+	})
+	if err != nil {
+		return err
+	}
+
+	// every entry is appended as soon as it is recorded so that an interrupted run can still be undone
+	if j.manifest == nil {
+		j.manifest, err = os.OpenFile(filepath.Join(j.dir, journalManifest), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := j.manifest.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	j.recorded++
+	return nil
+}
+
+// Close finishes the run. A run that recorded nothing is removed.
+func (j *Journal) Close() error {
+	if j.manifest != nil {
+		if err := j.manifest.Close(); err != nil {
+			return err
+		}
+		j.manifest = nil
+	}
+	if j.recorded == 0 {
+		return os.RemoveAll(j.dir)
+	}
+	return nil
+}
+
+// readManifest returns the entries of a run. An entry cut short at the end of the manifest is left out:
+// it was being recorded when the run was interrupted, before its note was written.
+func readManifest(dir string) ([]journalEntry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, journalManifest))
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	entries := make([]journalEntry, 0, len(lines))
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// JournalRuns lists the recorded runs of a vault, oldest first
+func JournalRuns(basepath string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(basepath, filepath.FromSlash(JournalDir)))
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			runs = append(runs, entry.Name())
+		}
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// Undo restores the notes recorded in a run, or in the latest run when run is empty, and removes the run.
+// It refuses to restore anything when a note was modified after the conversion.
+func Undo(basepath, run string) (string, error) {
+	if run == "" {
+		runs, err := JournalRuns(basepath)
+		if err != nil {
+			return "", err
+		}
+		if len(runs) == 0 {
+			return "", errors.New("no conversion to undo")
+		}
+		run = runs[len(runs)-1]
+	}
+
+	dir := filepath.Join(basepath, filepath.FromSlash(JournalDir), run)
+	entries, err := readManifest(dir)
+	if err != nil {
+		return run, fmt.Errorf("read journal of run %s: %w", run, err)
+	}
+
+	// check every note first so that nothing is restored when one of them was changed by someone else
+	restore := make([]journalEntry, 0, len(entries))
+	modified := make([]string, 0)
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(basepath, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return run, err
+		}
+		switch checksum(content) {
+		case entry.Converted:
+			restore = append(restore, entry)
+		case entry.Original:
+			// the conversion was never written
+		default:
+			modified = append(modified, entry.Path)
+		}
+	}
+	if len(modified) > 0 {
+		return run, fmt.Errorf("refusing to undo run %s, files were modified after the conversion: %s", run, strings.Join(modified, ", "))
+	}
+
+	for _, entry := range restore {
+		original, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return run, err
+		}
+		if err := writeFileAtomic(filepath.Join(basepath, filepath.FromSlash(entry.Path)), original, UpdateMtime); err != nil {
+			return run, err
+		}
+	}
+
+	return run, os.RemoveAll(dir)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
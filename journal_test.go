@@ -0,0 +1,114 @@
+package olconv
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndo(t *testing.T) {
+	tempDir := t.TempDir()
+	copyTestVault(t, "testdata/sample_vault", tempDir)
+
+	indexPath := filepath.Join(tempDir, "index.md")
+	originalIndex, err := os.ReadFile(indexPath)
+	require.NoError(t, err)
+
+	journal, err := NewJournal(tempDir)
+	require.NoError(t, err)
+	_, err = ConvertVault(context.Background(), tempDir, Options{Direction: ToWikilink, Journal: journal})
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+
+	runs, err := JournalRuns(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{journal.Run()}, runs)
+
+	// the journal is not treated as part of the vault
+	files, err := ListMdFiles(tempDir)
+	require.NoError(t, err)
+	assert.NotContains(t, files, filepath.Join(tempDir, JournalDir, journal.Run(), "index.md"))
+
+	run, err := Undo(tempDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, journal.Run(), run)
+
+	restoredIndex, err := os.ReadFile(indexPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(originalIndex), string(restoredIndex))
+
+	runs, err = JournalRuns(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	_, err = Undo(tempDir, "")
+	assert.Error(t, err)
+}
+
+func TestUndo_ModifiedFile(t *testing.T) {
+	tempDir := t.TempDir()
+	copyTestVault(t, "testdata/sample_vault", tempDir)
+
+	journal, err := NewJournal(tempDir)
+	require.NoError(t, err)
+	_, err = ConvertVault(context.Background(), tempDir, Options{Direction: ToWikilink, Journal: journal})
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+
+	indexPath := filepath.Join(tempDir, "index.md")
+	basicPath := filepath.Join(tempDir, "basic.md")
+	convertedBasic, err := os.ReadFile(basicPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(indexPath, []byte("edited after conversion\n"), 0644))
+
+	_, err = Undo(tempDir, journal.Run())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "index.md")
+
+	// nothing is restored
+	basicContent, err := os.ReadFile(basicPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(convertedBasic), string(basicContent))
+
+	runs, err := JournalRuns(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{journal.Run()}, runs)
+}
+
+func TestJournal_CloseWithoutChanges(t *testing.T) {
+	tempDir := t.TempDir()
+
+	journal, err := NewJournal(tempDir)
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+
+	runs, err := JournalRuns(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
+
+func TestUndo_InterruptedRun(t *testing.T) {
+	dir := writeVault(t, map[string]string{"a.md": "[[b]]\n", "b.md": "[[a]]\n"})
+
+	journal, err := NewJournal(dir)
+	require.NoError(t, err)
+	require.NoError(t, journal.Record(filepath.Join(dir, "a.md"), []byte("[[b]]\n"), []byte("[b](b.md)\n")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte("[b](b.md)\n"), 0644))
+	require.NoError(t, journal.Record(filepath.Join(dir, "b.md"), []byte("[[a]]\n"), []byte("[a](a.md)\n")))
+	assert.Equal(t, 2, journal.Len())
+	require.NoError(t, journal.Close())
+
+	// the run stops while the last entry is being appended, before b.md is written
+	manifest := filepath.Join(dir, JournalDir, journal.Run(), journalManifest)
+	data, err := os.ReadFile(manifest)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(manifest, data[:len(data)-10], 0644))
+
+	_, err = Undo(dir, journal.Run())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a.md": "[[b]]\n", "b.md": "[[a]]\n"}, readVault(t, dir, "a.md", "b.md"))
+}
@@ -0,0 +1,148 @@
+package olconv
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+// LinkKind is the syntax a link is written in
+type LinkKind string
+
+const (
+	// MarkdownLinkKind is a link written as [text](path.md)
+	MarkdownLinkKind LinkKind = "markdown"
+	// WikilinkKind is a link written as [[path|text]]
+	WikilinkKind LinkKind = "wikilink"
+)
+
+// Link is a Markdown link or wikilink found in a document
+type Link struct {
+	Kind LinkKind `json:"kind"`
+	// Target is the destination of the link without the fragment, as written.
+	// It is empty for links to a heading of the same note, such as [[#Heading]].
+	Target string `json:"target"`
+	// Fragment is the heading or block reference (^blockid) after #, as written
+	Fragment string `json:"fragment,omitempty"`
+	// Text is the text displayed for the link: the title or alias, or for a wikilink without one,
+	// the target as Obsidian shows it, e.g. "note > Heading" for [[note#Heading]]
+	Text string `json:"text"`
+	// Size is the size suffix of an embed, e.g. 300 for ![[image.png|300]]
+	Size  string `json:"size,omitempty"`
+	Embed bool   `json:"embed"`
+	// Raw is the link as written, including the ! of an embed
+	Raw string `json:"raw"`
+	// Offset and End are the byte offsets of the start and the end of Raw in the document
+	Offset int `json:"offset"`
+	End    int `json:"end"`
+	// Line and Column are the 1-based position of the link, Column counting bytes
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// ExtractLinks returns the links of a document in the order they appear.
+// Links in the YAML frontmatter, in code blocks, code spans and HTML blocks are left out, as they are not converted.
+func ExtractLinks(r io.Reader) ([]Link, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := splitDocument(string(content))
+	lines := doc.lines
+
+	body := 0
+	if end := frontmatterEnd(lines); end != -1 {
+		body = end + 1
+	}
+
+	links := make([]Link, 0)
+	tokens := tokenize(lines[body:])
+	offset := len(doc.bom)
+	for i, line := range lines {
+		if i >= body && tokens[i-body].kind == textBlock {
+			for _, link := range lineLinks(line, tokens[i-body].carry) {
+				link.Line = i + 1
+				link.Column = link.Offset + 1
+				link.Offset += offset
+				link.End += offset
+				links = append(links, link)
+			}
+		}
+		offset += len(line) + len(doc.ends[i])
+	}
+	return links, nil
+}
+
+// lineLinks returns the links of a single line in the order they appear, with offsets from the start of the line.
+// carry tells the code spans the line shares with the rest of its paragraph.
+func lineLinks(line string, carry spanCarry) []Link {
+	links := make([]Link, 0)
+
+	p := Parser{
+		mdLinks: []mdLink{},
+		carry:   carry,
+	}
+	p.parse(line)
+	for _, mdLink := range p.mdLinks {
+		start := mdLink.titleStartPos
+		if mdLink.embed {
+			start--
+		}
+		target, fragment, _ := strings.Cut(mdLink.destination, "#")
+		text, size := mdLink.title, ""
+		if mdLink.embed {
+			text, size = splitEmbedSize(text)
+		}
+		links = append(links, Link{
+			Kind:     MarkdownLinkKind,
+			Target:   target,
+			Fragment: fragment,
+			Text:     text,
+			Size:     size,
+			Embed:    mdLink.embed,
+			Raw:      line[start : mdLink.destinationEndPos+1],
+			Offset:   start,
+			End:      mdLink.destinationEndPos + 1,
+		})
+	}
+
+	wp := WikilinkParser{
+		wikilinks: []wikilink{},
+		carry:     carry,
+	}
+	wp.parse(line)
+	for _, wlink := range wp.wikilinks {
+		start := wlink.startPos
+		if wlink.embed {
+			start--
+		}
+		text := wlink.title
+		if text == "" {
+			switch {
+			case wlink.destination == "":
+				text = wlink.fragment
+			case wlink.fragment != "":
+				text = wlink.destination + " > " + wlink.fragment
+			default:
+				text = wlink.destination
+			}
+		}
+		links = append(links, Link{
+			Kind:     WikilinkKind,
+			Target:   wlink.destination,
+			Fragment: wlink.fragment,
+			Text:     text,
+			Size:     wlink.size,
+			Embed:    wlink.embed,
+			Raw:      line[start : wlink.endPos+1],
+			Offset:   start,
+			End:      wlink.endPos + 1,
+		})
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		return links[i].Offset < links[j].Offset
+	})
+	return links
+}
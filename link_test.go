@@ -0,0 +1,36 @@
+package olconv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractLinks(t *testing.T) {
+	doc := "---\r\n" +
+		"up: \"[[index]]\"\r\n" +
+		"---\r\n" +
+		"See [Setup](note.md#Setup%20steps) and [[note#^abc|quote]].\r\n" +
+		"```\r\n" +
+		"[[in code block]]\r\n" +
+		"```\r\n" +
+		"![[image.png|300]] `[[code span]]` [[#Local]] ![alt|200x100](a%20b.png) [[sub/note#Heading]]\n"
+
+	links, err := ExtractLinks(strings.NewReader(doc))
+	require.NoError(t, err)
+
+	want := []Link{
+		{Kind: MarkdownLinkKind, Target: "note.md", Fragment: "Setup%20steps", Text: "Setup", Raw: "[Setup](note.md#Setup%20steps)", Offset: 31, End: 61, Line: 4, Column: 5},
+		{Kind: WikilinkKind, Target: "note", Fragment: "^abc", Text: "quote", Raw: "[[note#^abc|quote]]", Offset: 66, End: 85, Line: 4, Column: 40},
+		{Kind: WikilinkKind, Target: "image.png", Text: "image.png", Size: "300", Embed: true, Raw: "![[image.png|300]]", Offset: 117, End: 135, Line: 8, Column: 1},
+		{Kind: WikilinkKind, Fragment: "Local", Text: "Local", Raw: "[[#Local]]", Offset: 152, End: 162, Line: 8, Column: 36},
+		{Kind: MarkdownLinkKind, Target: "a%20b.png", Text: "alt", Size: "200x100", Embed: true, Raw: "![alt|200x100](a%20b.png)", Offset: 163, End: 188, Line: 8, Column: 47},
+		{Kind: WikilinkKind, Target: "sub/note", Fragment: "Heading", Text: "sub/note > Heading", Raw: "[[sub/note#Heading]]", Offset: 189, End: 209, Line: 8, Column: 73},
+	}
+	assert.Equal(t, want, links)
+	for _, link := range links {
+		assert.Equal(t, link.Raw, doc[link.Offset:link.End])
+	}
+}
@@ -0,0 +1,302 @@
+package olconv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MoveNote moves a file of the vault and rewrites every Markdown link and wikilink pointing to it.
+// from and to are paths of the file before and after the move; when to is an existing directory, the file keeps its name.
+// Wikilinks written with the shortest path are rewritten to stay unique, or to drop a path that is no longer needed,
+// and the relative links of the moved note itself are updated to its new location. Links in the frontmatter are left untouched,
+// as are the notes listed in .olconvignore. The rewritten notes are written to temporary files before the file is moved,
+// and replace the notes once it is.
+func MoveNote(basepath, from, to string, mtime MtimePolicy) error {
+	if info, err := os.Stat(to); err == nil && info.IsDir() {
+		to = filepath.Join(to, filepath.Base(from))
+	}
+
+	fromFile, err := vaultFile(basepath, from)
+	if err != nil {
+		return err
+	}
+	toFile, err := vaultFile(basepath, to)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(fromFile); err != nil {
+		return err
+	} else if info.IsDir() {
+		return fmt.Errorf("%s is a directory", from)
+	}
+	if _, err := os.Stat(toFile); err == nil {
+		return fmt.Errorf("%s already exists", to)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// notes listed in .olconvignore are left unchanged, but links to them are still resolved
+	filter, err := newVaultFilter(basepath, Options{IndexExcluded: true})
+	if err != nil {
+		return err
+	}
+	notes, vaultFiles, err := filter.list()
+	if err != nil {
+		return err
+	}
+	movedFiles := make([]string, 0, len(vaultFiles))
+	for _, file := range vaultFiles {
+		if file == fromFile {
+			file = toFile
+		}
+		movedFiles = append(movedFiles, file)
+	}
+
+	m := &noteMove{
+		before: NewConverter(basepath, FileListToMap(vaultFiles)),
+		after:  NewConverter(basepath, FileListToMap(movedFiles)),
+		from:   fromFile,
+		to:     toFile,
+	}
+
+	// rewritten notes are written to temporary files before the move,
+	// so that a note that cannot be written leaves the vault as it was
+	staged := make([]stagedFile, 0)
+	defer func() {
+		for _, file := range staged {
+			os.Remove(file.tmp)
+		}
+	}()
+	for _, note := range notes {
+		content, err := os.ReadFile(note)
+		if err != nil {
+			return err
+		}
+		newPath := note
+		if note == fromFile {
+			newPath = toFile
+		}
+		converted := m.rewrite(string(content), note, newPath)
+		if converted == string(content) {
+			continue
+		}
+		file, err := stageFile(note, []byte(converted), mtime)
+		if err != nil {
+			return err
+		}
+		// the moved note replaces the file at its new path, unless it is a symbolic link to a file that stays in place
+		if note == fromFile {
+			if info, err := os.Lstat(note); err == nil && info.Mode()&os.ModeSymlink == 0 {
+				file.path = toFile
+			}
+		}
+		staged = append(staged, file)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(toFile), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(fromFile, toFile); err != nil {
+		return err
+	}
+	for len(staged) > 0 {
+		if err := os.Rename(staged[0].tmp, staged[0].path); err != nil {
+			return err
+		}
+		staged = staged[1:]
+	}
+	return nil
+}
+
+// vaultFile returns path as it appears in the file listing of the vault, failing when it is outside the vault
+func vaultFile(basepath, path string) (string, error) {
+	absBase, err := filepath.Abs(basepath)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absBase, absPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not in the vault %s", path, basepath)
+	}
+	return filepath.Join(basepath, rel), nil
+}
+
+// noteMove rewrites links for a move, resolving them against the vault before the move and writing them for the vault after it
+type noteMove struct {
+	before *Converter
+	after  *Converter
+	from   string
+	to     string
+}
+
+// rewrite updates the links of a note located at oldPath before the move and at newPath after it.
+// Lines are rewritten in place so that everything but the links is kept byte for byte.
+func (m *noteMove) rewrite(content, oldPath, newPath string) string {
+	note := &noteMove{
+		before: m.before.forNote(oldPath),
+		after:  m.after.forNote(newPath),
+		from:   m.from,
+		to:     m.to,
+	}
+
+	doc := splitDocument(content)
+	lines := doc.lines
+	body := 0
+	if end := frontmatterEnd(lines); end != -1 {
+		body = end + 1
+	}
+	tokens := tokenize(lines[body:])
+	for i := body; i < len(lines); i++ {
+		token := tokens[i-body]
+		if token.kind != textBlock {
+			continue
+		}
+		// links are rewritten outside the code spans, which stay as they are
+		lines[i] = note.rewriteWikilinks(note.rewriteMdLinks(lines[i], token.carry), token.carry)
+	}
+	return doc.String()
+}
+
+// moved returns where file is after the move
+func (m *noteMove) moved(file string) string {
+	if file == m.from {
+		return m.to
+	}
+	return file
+}
+
+func (m *noteMove) rewriteMdLinks(line string, carry spanCarry) string {
+	p := Parser{
+		mdLinks: []mdLink{},
+		carry:   carry,
+	}
+	p.parse(line)
+
+	// start from last index to avoid index misalignment due to re-slicing
+	for i := len(p.mdLinks) - 1; i >= 0; i-- {
+		link := p.mdLinks[i]
+		destination, fragment, hasFragment := strings.Cut(link.destination, "#")
+		if destination == "" || urlSchemePattern.MatchString(destination) {
+			continue
+		}
+		file, fallback, ok := m.before.findDestination(destination)
+		if !ok {
+			continue
+		}
+		// a destination found from the vault root or by name still points to its file wherever the note is
+		if fallback && file != m.from {
+			continue
+		}
+
+		newDestination := m.markdownDestination(destination, m.moved(file), fallback)
+		if hasFragment {
+			newDestination += "#" + fragment
+		}
+		if newDestination == link.destination {
+			continue
+		}
+		if link.angle {
+			newDestination = "<" + newDestination + ">"
+		}
+		line = line[:link.pathStartPos] + newDestination + line[link.pathEndPos:]
+	}
+	return line
+}
+
+// markdownDestination writes the destination of a Markdown link to file in the same form as the original destination:
+// from the vault root or relative to the note, and with or without ./ and the .md extension.
+// A destination that was not relative to the note is written as the shortest path when it had no directory.
+// The path is percent-encoded, but spaces are kept when the original destination has literal spaces.
+func (m *noteMove) markdownDestination(original, file string, fallback bool) string {
+	decoded, _ := decodeDestination(original)
+
+	var destination string
+	switch {
+	case strings.HasPrefix(original, "/"):
+		destination = "/" + m.after.vaultPath(file)
+	case fallback:
+		destination = m.after.vaultPath(file)
+		if !strings.Contains(decoded, "/") && !strings.Contains(m.after.Resolver.WikilinkName(file), "/") {
+			destination = filepath.Base(file)
+		}
+	default:
+		destination = m.after.relativeFromNote(file)
+		if strings.HasPrefix(original, "./") && !strings.HasPrefix(destination, "../") {
+			destination = "./" + destination
+		}
+	}
+	if !isAttachment(file) && !strings.HasSuffix(decoded, ".md") {
+		destination = strings.TrimSuffix(destination, ".md")
+	}
+	if strings.Contains(original, " ") {
+		return angleDestinationEscaper.Replace(destination)
+	}
+	return encodeDestination(destination)
+}
+
+func (m *noteMove) rewriteWikilinks(line string, carry spanCarry) string {
+	wp := WikilinkParser{
+		wikilinks: []wikilink{},
+		carry:     carry,
+	}
+	wp.parse(line)
+
+	// start from last index to avoid index misalignment due to re-slicing
+	for i := len(wp.wikilinks) - 1; i >= 0; i-- {
+		wlink := wp.wikilinks[i]
+		if wlink.destination == "" {
+			continue
+		}
+		matches := m.before.matchWikilink(wlink.destination)
+		if len(matches) != 1 {
+			continue
+		}
+
+		target, ok := m.wikilinkTarget(wlink.destination, matches[0])
+		if !ok || target == wlink.destination {
+			continue
+		}
+
+		// [[destination#heading|title]]: only the destination is replaced
+		content := line[wlink.startPos+2 : wlink.endPos-1]
+		rest := ""
+		if j := strings.IndexAny(content, "#|"); j != -1 {
+			rest = content[j:]
+		}
+		// keep the displayed text of a bare link that gets a path
+		if rest == "" && !wlink.embed && !strings.Contains(wlink.destination, "/") && strings.Contains(target, "/") {
+			rest = "|" + extractFilename(target)
+		}
+		line = line[:wlink.startPos+2] + target + rest + line[wlink.endPos-1:]
+	}
+	return line
+}
+
+// wikilinkTarget writes the target of a wikilink to file in the same form as the original target:
+// the path from the vault root, the path relative to the note, or the shortest unique path.
+// Links to other files are only rewritten when they use the shortest path, which may change with the move.
+func (m *noteMove) wikilinkTarget(original, file string) (string, bool) {
+	target := m.moved(file)
+
+	switch {
+	case original == trimNoteExtension(m.before.vaultPath(file)) && strings.Contains(original, "/"):
+		return trimNoteExtension(m.after.vaultPath(target)), target != file
+	case strings.HasPrefix(original, "./") || strings.HasPrefix(original, "../"):
+		relative := trimNoteExtension(m.after.relativeFromNote(target))
+		if strings.HasPrefix(original, "./") && !strings.HasPrefix(relative, "../") {
+			relative = "./" + relative
+		}
+		return relative, true
+	case original == m.before.Resolver.WikilinkName(file) || target != file:
+		return m.after.Resolver.WikilinkName(target), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,169 @@
+package olconv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeVault(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func readVault(t *testing.T, dir string, names ...string) map[string]string {
+	files := make(map[string]string)
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		files[name] = string(content)
+	}
+	return files
+}
+
+func TestMoveNote(t *testing.T) {
+	dir := writeVault(t, map[string]string{
+		"index.md": "# Index\n\n" +
+			"[A](a.md) [A2](./a.md#Top) [[a]] [[a#Top|top]] ![[img.png|300]]\n" +
+			"[[b]] [[x/b|B]] [c](dir%20c/c.md)\n" +
+			"```\n[[a]]\n```\n",
+		"a.md":         "[index](index.md) [[x/b]] [[./x/b]] ![img](img.png)",
+		"x/b.md":       "b\n",
+		"dir c/c.md":   "[a](../a.md) [[a]] [b](../x/b.md)\n",
+		"img.png":      "",
+		"untouched.md": "[[x/b]]\n",
+	})
+
+	err := MoveNote(dir, filepath.Join(dir, "a.md"), filepath.Join(dir, "dir c", "b.md"), UpdateMtime)
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(dir, "a.md"))
+	assert.Equal(t, map[string]string{
+		"index.md": "# Index\n\n" +
+			"[A](dir%20c/b.md) [A2](./dir%20c/b.md#Top) [[dir c/b|b]] [[dir c/b#Top|top]] ![[img.png|300]]\n" +
+			"[[x/b|b]] [[x/b|B]] [c](dir%20c/c.md)\n" +
+			"```\n[[a]]\n```\n",
+		"dir c/b.md":   "[index](../index.md) [[x/b]] [[../x/b]] ![img](../img.png)",
+		"dir c/c.md":   "[a](b.md) [[dir c/b|b]] [b](../x/b.md)\n",
+		"untouched.md": "[[x/b]]\n",
+	}, readVault(t, dir, "index.md", "dir c/b.md", "dir c/c.md", "untouched.md"))
+}
+
+func TestMoveNote_Unambiguous(t *testing.T) {
+	dir := writeVault(t, map[string]string{
+		"index.md":   "[[x/b]] [[y/b|other]] [[p/x/b]]\n",
+		"p/x/b.md":   "",
+		"y/b.md":     "",
+		"y/other.md": "",
+	})
+
+	// into an existing directory
+	err := MoveNote(dir, filepath.Join(dir, "y", "b.md"), filepath.Join(dir, "p"), UpdateMtime)
+	require.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(dir, "p", "b.md"))
+	assert.Equal(t, map[string]string{
+		"index.md": "[[x/b]] [[p/b|other]] [[p/x/b]]\n",
+	}, readVault(t, dir, "index.md"))
+
+	// the last note with the name no longer needs a path
+	err = MoveNote(dir, filepath.Join(dir, "p", "b.md"), filepath.Join(dir, "y", "c.md"), UpdateMtime)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"index.md": "[[b]] [[y/c|other]] [[p/x/b]]\n",
+	}, readVault(t, dir, "index.md"))
+}
+
+func TestMoveNote_LineEndings(t *testing.T) {
+	dir := writeVault(t, map[string]string{
+		"index.md": "\ufeff---\r\nup: \"[[a]]\"\r\n---\r\n[[a]] and [A](a.md)\r\n```\r\n[[a]]\r\n```\r\n",
+		"a.md":     "# A",
+	})
+
+	err := MoveNote(dir, filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md"), UpdateMtime)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"index.md": "\ufeff---\r\nup: \"[[a]]\"\r\n---\r\n[[b]] and [A](b.md)\r\n```\r\n[[a]]\r\n```\r\n",
+		"b.md":     "# A",
+	}, readVault(t, dir, "index.md", "b.md"))
+}
+
+func TestMoveNote_TitlesAndAngleDestinations(t *testing.T) {
+	dir := writeVault(t, map[string]string{
+		"index.md": "[A](a.md \"the title\") and [A](<a.md>)\n",
+		"a.md":     "# A",
+	})
+
+	err := MoveNote(dir, filepath.Join(dir, "a.md"), filepath.Join(dir, "sub", "b.md"), UpdateMtime)
+	require.NoError(t, err)
+	assert.Equal(t, "[A](sub/b.md \"the title\") and [A](<sub/b.md>)\n", readVault(t, dir, "index.md")["index.md"])
+}
+
+func TestMoveNote_ShortestMarkdownLinks(t *testing.T) {
+	dir := writeVault(t, map[string]string{
+		"sub/index.md": "[A](a.md) and [B](b.md) and [D](dir/d.md)\n",
+		"a.md":         "# A",
+		"b.md":         "# B",
+		"dir/d.md":     "# D",
+	})
+
+	err := MoveNote(dir, filepath.Join(dir, "a.md"), filepath.Join(dir, "notes", "c.md"), UpdateMtime)
+	require.NoError(t, err)
+	assert.Equal(t, "[A](c.md) and [B](b.md) and [D](dir/d.md)\n", readVault(t, dir, "sub/index.md")["sub/index.md"])
+}
+
+func TestNoteMove_RewriteKeepsConverters(t *testing.T) {
+	files := []string{"vault/a.md", "vault/sub/index.md"}
+	m := &noteMove{
+		before: NewConverter("vault", FileListToMap(files)),
+		after:  NewConverter("vault", FileListToMap([]string{"vault/b.md", "vault/sub/index.md"})),
+		from:   "vault/a.md",
+		to:     "vault/b.md",
+	}
+
+	assert.Equal(t, "[A](../b.md)\n", m.rewrite("[A](../a.md)\n", "vault/sub/index.md", "vault/sub/index.md"))
+	// the Converters are shared by every note and are not tied to the rewritten one
+	assert.Empty(t, m.before.notePath)
+	assert.Empty(t, m.after.notePath)
+}
+
+func TestMoveNote_Errors(t *testing.T) {
+	dir := writeVault(t, map[string]string{
+		"a.md": "",
+		"b.md": "",
+	})
+
+	assert.Error(t, MoveNote(dir, filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md"), UpdateMtime))
+	assert.Error(t, MoveNote(dir, filepath.Join(dir, "missing.md"), filepath.Join(dir, "c.md"), UpdateMtime))
+	assert.Error(t, MoveNote(dir, filepath.Join(dir, "a.md"), filepath.Join(dir, "..", "a.md"), UpdateMtime))
+	assert.FileExists(t, filepath.Join(dir, "a.md"))
+}
+
+func TestMoveNote_FailedMoveLeavesVault(t *testing.T) {
+	vault := map[string]string{
+		"a.md":     "[[b]] [B](b.md)\n",
+		"b.md":     "[[a]]\n",
+		"sub/c.md": "[A](../a.md)\n",
+	}
+	dir := writeVault(t, vault)
+	// the directory of the new path cannot be created
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "moved")))
+
+	assert.Error(t, MoveNote(dir, filepath.Join(dir, "a.md"), filepath.Join(dir, "moved", "a.md"), UpdateMtime))
+	assert.Equal(t, vault, readVault(t, dir, "a.md", "b.md", "sub/c.md"))
+	// and the rewritten notes written before the move are removed
+	for _, pattern := range []string{".*.olconv-*", "sub/.*.olconv-*"} {
+		tmp, err := filepath.Glob(filepath.Join(dir, pattern))
+		require.NoError(t, err)
+		assert.Empty(t, tmp)
+	}
+}
@@ -0,0 +1,75 @@
+package olconv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ObsidianConfig holds the link settings of a vault, read from .obsidian/app.json
+type ObsidianConfig struct {
+	// NewLinkFormat is "shortest", "relative" or "absolute"
+	NewLinkFormat string `json:"newLinkFormat"`
+	// UseMarkdownLinks is true when Obsidian writes Markdown links instead of wikilinks
+	UseMarkdownLinks bool `json:"useMarkdownLinks"`
+	// AttachmentFolderPath is where Obsidian stores new attachments
+	AttachmentFolderPath string `json:"attachmentFolderPath"`
+}
+
+// ReadObsidianConfig reads the link settings of the vault. It returns nil when the vault has no .obsidian/app.json.
+func ReadObsidianConfig(basepath string) (*ObsidianConfig, error) {
+	path := filepath.Join(basepath, ".obsidian", "app.json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ObsidianConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Direction returns the conversion that produces the link format configured in the vault
+func (cfg *ObsidianConfig) Direction() LinkDirection {
+	if cfg.UseMarkdownLinks {
+		return ToMarkdown
+	}
+	return ToWikilink
+}
+
+// PathStyle returns the path style matching the newLinkFormat setting.
+// An unset or unknown format uses DefaultPath.
+func (cfg *ObsidianConfig) PathStyle() PathStyle {
+	style, _ := ParsePathStyle(cfg.NewLinkFormat)
+	return style
+}
+
+// FindVaultRoot returns the absolute path of the closest directory containing path that has an .obsidian directory.
+// It returns false when path is not in a vault.
+func FindVaultRoot(path string) (string, bool) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		if info, err := os.Stat(filepath.Join(dir, ".obsidian")); err == nil && info.IsDir() {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
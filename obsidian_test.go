@@ -0,0 +1,90 @@
+package olconv
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadObsidianConfig(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// vault without configuration
+	config, err := ReadObsidianConfig(tempDir)
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	require.NoError(t, os.Mkdir(filepath.Join(tempDir, ".obsidian"), 0755))
+	appJSON := filepath.Join(tempDir, ".obsidian", "app.json")
+
+	require.NoError(t, os.WriteFile(appJSON, []byte(`{"newLinkFormat": "relative", "useMarkdownLinks": true, "attachmentFolderPath": "./assets", "alwaysUpdateLinks": true}`), 0644))
+	config, err = ReadObsidianConfig(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, &ObsidianConfig{
+		NewLinkFormat:        "relative",
+		UseMarkdownLinks:     true,
+		AttachmentFolderPath: "./assets",
+	}, config)
+	assert.Equal(t, ToMarkdown, config.Direction())
+	assert.Equal(t, RelativePath, config.PathStyle())
+
+	require.NoError(t, os.WriteFile(appJSON, []byte(`{}`), 0644))
+	config, err = ReadObsidianConfig(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, ToWikilink, config.Direction())
+	assert.Equal(t, DefaultPath, config.PathStyle())
+
+	require.NoError(t, os.WriteFile(appJSON, []byte(`{`), 0644))
+	_, err = ReadObsidianConfig(tempDir)
+	assert.Error(t, err)
+}
+
+func TestLinkSettings_Integration(t *testing.T) {
+	// Create temporary test directory
+	tempDir := t.TempDir()
+
+	// Copy test files to temp directory
+	copyTestVault(t, "testdata/sample_vault", tempDir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(tempDir, ".obsidian"), 0755))
+	err := os.WriteFile(filepath.Join(tempDir, ".obsidian", "app.json"), []byte(`{"newLinkFormat": "absolute", "attachmentFolderPath": "attachments"}`), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(tempDir, "sub1", "links.md"), []byte("[[basic]] ![[new.png]]\n"), 0644)
+	require.NoError(t, err)
+
+	_, err = ConvertVault(context.Background(), tempDir, Options{Direction: ToMarkdown})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "sub1", "links.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "[basic](basic.md) ![new.png](attachments/new.png)\n", string(content))
+
+	_, err = ConvertVault(context.Background(), tempDir, Options{Direction: ToWikilink})
+	require.NoError(t, err)
+
+	content, err = os.ReadFile(filepath.Join(tempDir, "index.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "[[notes/important|Notes Directory]]")
+}
+
+func TestFindVaultRoot(t *testing.T) {
+	tempDir := t.TempDir()
+	vault := filepath.Join(tempDir, "vault")
+	require.NoError(t, os.MkdirAll(filepath.Join(vault, ".obsidian"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(vault, "sub", "dir"), 0755))
+
+	root, ok := FindVaultRoot(filepath.Join(vault, "sub", "dir", "note.md"))
+	assert.True(t, ok)
+	assert.Equal(t, vault, root)
+
+	root, ok = FindVaultRoot(filepath.Join(vault, "sub"))
+	assert.True(t, ok)
+	assert.Equal(t, vault, root)
+
+	_, ok = FindVaultRoot(tempDir)
+	assert.False(t, ok)
+}
@@ -0,0 +1,102 @@
+package olconv
+
+import (
+	"io"
+)
+
+// Options configures a conversion.
+// The zero value converts Markdown links to wikilinks whatever the useMarkdownLinks setting of the vault,
+// which ObsidianConfig.Direction reads; only the newLinkFormat and attachmentFolderPath settings are followed.
+type Options struct {
+	// Direction is the conversion applied to the links by ConvertVault and ConvertReader.
+	// NewConverter ignores it, as Converter.Convert takes the direction of every conversion.
+	Direction LinkDirection
+	// PathStyle controls how the paths of converted links are written.
+	// ConvertVault uses the newLinkFormat of the vault for DefaultPath.
+	PathStyle PathStyle
+	// Encoding controls how spaces in the paths of converted Markdown links are written
+	Encoding EncodingPolicy
+	// AnchorStyle controls how heading fragments are written when converting to Markdown links
+	AnchorStyle AnchorStyle
+	// FrontmatterMode controls whether links in the YAML frontmatter are converted
+	FrontmatterMode FrontmatterMode
+	// AttachmentFolder is where attachments that cannot be found are assumed to be.
+	// ConvertVault uses the attachmentFolderPath of the vault when it is empty.
+	AttachmentFolder string
+	// Resolver finds the files links point to, a FileMapResolver of the vault when nil
+	Resolver Resolver
+	// Rewrite is called for every link before it is converted, see Converter.Rewrite
+	Rewrite RewriteFunc
+	// Include restricts the converted notes to the ones matching these globs, such as notes/**.
+	// A glob matching a directory matches every file in it.
+	Include []string
+	// Exclude lists globs of files that are neither converted nor, unless IndexExcluded, linked to.
+	// Files listed in the .olconvignore files of the vault are excluded as well.
+	Exclude []string
+	// GitIgnore excludes the files listed in the .gitignore files of the vault
+	GitIgnore bool
+	// IndexExcluded resolves links to excluded files, which are still left unchanged
+	IndexExcluded bool
+
+	// The following options only apply to ConvertVault.
+
+	// Files restricts the conversion to these notes of the vault. Every note is converted when it is empty.
+	Files []string
+	// DryRun converts the notes without writing them
+	DryRun bool
+	// Diff receives a unified diff of every note that changes, when not nil
+	Diff io.Writer
+	// Mtime decides the modification time of rewritten notes
+	Mtime MtimePolicy
+	// Journal records the original content of every rewritten note, when not nil
+	Journal *Journal
+	// Jobs is the number of notes converted concurrently, GOMAXPROCS when it is not positive.
+	// The Resolver and Rewrite hook are then called from several goroutines; notes are still written in order.
+	Jobs int
+	// ContinueOnError goes on with the other notes when a note cannot be converted or written,
+	// instead of stopping at the first error
+	ContinueOnError bool
+}
+
+// Option sets a field of the Options of a Converter
+type Option func(*Options)
+
+// WithOptions sets every option at once
+func WithOptions(opts Options) Option {
+	return func(o *Options) { *o = opts }
+}
+
+// WithPathStyle sets how the paths of converted links are written
+func WithPathStyle(style PathStyle) Option {
+	return func(o *Options) { o.PathStyle = style }
+}
+
+// WithEncoding sets how spaces in the paths of converted Markdown links are written
+func WithEncoding(policy EncodingPolicy) Option {
+	return func(o *Options) { o.Encoding = policy }
+}
+
+// WithAnchorStyle sets how heading fragments are written in Markdown links
+func WithAnchorStyle(style AnchorStyle) Option {
+	return func(o *Options) { o.AnchorStyle = style }
+}
+
+// WithFrontmatterMode sets whether links in the YAML frontmatter are converted
+func WithFrontmatterMode(mode FrontmatterMode) Option {
+	return func(o *Options) { o.FrontmatterMode = mode }
+}
+
+// WithAttachmentFolder sets where attachments that cannot be found are assumed to be
+func WithAttachmentFolder(folder string) Option {
+	return func(o *Options) { o.AttachmentFolder = folder }
+}
+
+// WithResolver sets the Resolver used instead of the filemap
+func WithResolver(r Resolver) Option {
+	return func(o *Options) { o.Resolver = r }
+}
+
+// WithRewrite sets the hook called for every link
+func WithRewrite(fn RewriteFunc) Option {
+	return func(o *Options) { o.Rewrite = fn }
+}
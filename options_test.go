@@ -0,0 +1,32 @@
+package olconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConverter_Options(t *testing.T) {
+	resolver := NewFileMapResolver("other", nil)
+	c := NewConverter("vault", nil,
+		WithPathStyle(AbsolutePath),
+		WithAnchorStyle(GitHubAnchor),
+		WithEncoding(AngleBrackets),
+		WithFrontmatterMode(ConvertFrontmatter),
+		WithAttachmentFolder("attachments"),
+		WithResolver(resolver),
+	)
+
+	assert.Equal(t, AbsolutePath, c.PathStyle)
+	assert.Equal(t, GitHubAnchor, c.AnchorStyle)
+	assert.Equal(t, AngleBrackets, c.Encoding)
+	assert.Equal(t, ConvertFrontmatter, c.FrontmatterMode)
+	assert.Equal(t, "attachments", c.AttachmentFolder)
+	assert.Same(t, resolver, c.Resolver)
+
+	// later options override earlier ones
+	c = NewConverter("vault", nil, WithOptions(Options{PathStyle: RelativePath, AnchorStyle: GitHubAnchor}), WithPathStyle(ShortestPath))
+	assert.Equal(t, ShortestPath, c.PathStyle)
+	assert.Equal(t, GitHubAnchor, c.AnchorStyle)
+	assert.IsType(t, &FileMapResolver{}, c.Resolver)
+}
@@ -0,0 +1,242 @@
+package olconv
+
+import (
+	"regexp"
+	"strings"
+)
+
+type Parser struct {
+	mdLinks []mdLink
+	// carry tells the code spans the line shares with the rest of its paragraph
+	carry spanCarry
+}
+
+type mdLink struct {
+	embed       bool
+	title       string
+	destination string
+	// angle is set for destinations written as <path>, which may contain spaces and parentheses
+	angle bool
+	// hasLinkTitle is set for links with a title after the destination, as in [text](path.md "title")
+	hasLinkTitle  bool
+	titleStartPos int
+	titleEndPos   int
+	// positions of the parentheses around the destination
+	destinationStartPos int
+	destinationEndPos   int
+	// positions of the destination as written, including the <> of angle destinations
+	pathStartPos int
+	pathEndPos   int
+}
+
+func (p *Parser) parse(input string) {
+	var currentLink *mdLink
+	spans := findCodeSpans(input, p.carry)
+	skip := 0
+	for i, c := range input {
+		if i < skip || spans.contains(i) {
+			continue
+		}
+		switch c {
+		case '[':
+			currentLink = &mdLink{
+				embed:         i > 0 && input[i-1] == '!',
+				titleStartPos: i,
+			}
+		case ']':
+			if currentLink == nil {
+				continue
+			}
+			currentLink.titleEndPos = i
+			currentLink.title = input[currentLink.titleStartPos+1 : i]
+			if i+1 < len(input) && input[i+1] == '(' && parseLinkTail(input, i+1, currentLink) {
+				p.mdLinks = append(p.mdLinks, *currentLink)
+				skip = currentLink.destinationEndPos + 1
+			}
+			currentLink = nil
+		default:
+			continue
+		}
+	}
+}
+
+// linkTitlePattern matches the title at the end of the text between the parentheses of a link, with the whitespace around it
+var linkTitlePattern = regexp.MustCompile(`[ \t]+(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\((?:[^()\\]|\\.)*\))[ \t]*$`)
+
+// parseLinkTail parses the destination and optional title of an inline link starting with the parenthesis at open.
+// Destinations in <> follow CommonMark; other destinations may have balanced parentheses and,
+// unlike CommonMark, spaces when there is no title, as Obsidian accepts them.
+func parseLinkTail(input string, open int, link *mdLink) bool {
+	start := skipBlanks(input, open+1)
+	closing := -1
+	if start < len(input) && input[start] == '<' {
+		end := start + 1
+		for end < len(input) && input[end] != '>' {
+			if input[end] == '<' {
+				return false
+			}
+			if input[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(input) {
+			return false
+		}
+		link.angle = true
+		link.destination = input[start+1 : end]
+		link.pathStartPos, link.pathEndPos = start, end+1
+
+		i := skipBlanks(input, end+1)
+		if i > end+1 && i < len(input) && (input[i] == '"' || input[i] == '\'' || input[i] == '(') {
+			quote := input[i]
+			if quote == '(' {
+				quote = ')'
+			}
+			title := strings.IndexByte(input[i+1:], quote)
+			if title == -1 {
+				return false
+			}
+			i = skipBlanks(input, i+1+title+1)
+		}
+		if i >= len(input) || input[i] != ')' {
+			return false
+		}
+		closing = i
+	} else {
+		// find the closing parenthesis, skipping balanced ones and quoted titles
+		depth := 0
+		for i := start; i < len(input) && closing == -1; i++ {
+			switch input[i] {
+			case '\\':
+				i++
+			case '"', '\'':
+				if i > start && (input[i-1] == ' ' || input[i-1] == '\t') {
+					if end := strings.IndexByte(input[i+1:], input[i]); end != -1 {
+						i += end + 1
+					}
+				}
+			case '(':
+				depth++
+			case ')':
+				if depth == 0 {
+					closing = i
+				}
+				depth--
+			}
+		}
+		if closing == -1 {
+			return false
+		}
+		link.destination = strings.TrimRight(input[start:closing], " \t")
+		if m := linkTitlePattern.FindStringIndex(link.destination); m != nil {
+			link.destination = link.destination[:m[0]]
+		}
+		link.pathStartPos, link.pathEndPos = start, start+len(link.destination)
+	}
+
+	link.hasLinkTitle = linkTitlePattern.MatchString(input[link.pathEndPos:closing])
+	link.destinationStartPos = open
+	link.destinationEndPos = closing
+	return true
+}
+
+// skipBlanks returns the index of the first character from i that is not a space or a tab
+func skipBlanks(input string, i int) int {
+	for i < len(input) && (input[i] == ' ' || input[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// WikilinkParser parses WikiLinks in text
+type WikilinkParser struct {
+	wikilinks []wikilink
+	// carry tells the code spans the line shares with the rest of its paragraph
+	carry spanCarry
+}
+
+type wikilink struct {
+	embed       bool
+	destination string
+	fragment    string
+	title       string
+	size        string
+	startPos    int
+	endPos      int
+}
+
+func (wp *WikilinkParser) parse(input string) {
+	var currentWikilink *wikilink
+	spans := findCodeSpans(input, wp.carry)
+	i := 0
+
+	for i < len(input) {
+		if spans.contains(i) {
+			i++
+			continue
+		}
+		switch input[i] {
+		case '[':
+			// Check for [[
+			if i+1 < len(input) && input[i+1] == '[' {
+				currentWikilink = &wikilink{
+					embed:    i > 0 && input[i-1] == '!',
+					startPos: i,
+				}
+				i += 2 // Skip [[
+				continue
+			}
+		case ']':
+			if currentWikilink == nil {
+				i++
+				continue
+			}
+			// Check for ]]
+			if i+1 < len(input) && input[i+1] == ']' {
+				// Extract content between [[ and ]]
+				content := input[currentWikilink.startPos+2 : i]
+				currentWikilink.endPos = i + 1
+
+				// Parse content: check for | separator
+				if pipeIndex := strings.Index(content, "|"); pipeIndex != -1 {
+					currentWikilink.destination = strings.TrimSpace(content[:pipeIndex])
+					currentWikilink.title = strings.TrimSpace(content[pipeIndex+1:])
+				} else {
+					currentWikilink.destination = strings.TrimSpace(content)
+				}
+
+				// Split size suffix of embeds: ![[destination|title|300]]
+				if currentWikilink.embed {
+					currentWikilink.title, currentWikilink.size = splitEmbedSize(currentWikilink.title)
+				}
+
+				// Split heading fragment: [[destination#heading]]
+				if destination, fragment, ok := strings.Cut(currentWikilink.destination, "#"); ok {
+					currentWikilink.destination = strings.TrimSpace(destination)
+					currentWikilink.fragment = strings.TrimSpace(fragment)
+				}
+
+				wp.wikilinks = append(wp.wikilinks, *currentWikilink)
+				currentWikilink = nil
+				i += 2 // Skip ]]
+				continue
+			}
+		}
+		i++
+	}
+}
+
+var embedSizePattern = regexp.MustCompile(`^\d+(x\d+)?$`)
+
+// splitEmbedSize separates an Obsidian size suffix such as 300 or 300x200 from the title of an embed
+func splitEmbedSize(title string) (string, string) {
+	rest, size := "", title
+	if i := strings.LastIndex(title, "|"); i != -1 {
+		rest, size = title[:i], title[i+1:]
+	}
+	if !embedSizePattern.MatchString(size) {
+		return title, ""
+	}
+	return rest, size
+}
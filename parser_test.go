@@ -0,0 +1,29 @@
+package olconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser_readChar(t *testing.T) {
+	l := Parser{
+		mdLinks: []mdLink{},
+	}
+	l.parse("[note title](note/note%20with%20spaces.md) を実装する`")
+
+	t.Logf("%#v\n", l)
+}
+
+func TestWikilinkParser_parse(t *testing.T) {
+	wp := WikilinkParser{
+		wikilinks: []wikilink{},
+	}
+	wp.parse("[[note with spaces|note title]] を実装する")
+
+	assert.Len(t, wp.wikilinks, 1)
+	assert.Equal(t, "note with spaces", wp.wikilinks[0].destination)
+	assert.Equal(t, "note title", wp.wikilinks[0].title)
+	assert.Equal(t, 0, wp.wikilinks[0].startPos)
+	assert.Equal(t, 30, wp.wikilinks[0].endPos)
+}
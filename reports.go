@@ -0,0 +1,80 @@
+package olconv
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Backlinks returns the links pointing to a note, given by its path from the vault root, in the order of the graph
+func (g *Graph) Backlinks(note string) []Edge {
+	backlinks := make([]Edge, 0)
+	for _, e := range g.Edges {
+		if e.Target == note {
+			backlinks = append(backlinks, e)
+		}
+	}
+	return backlinks
+}
+
+// Orphans returns the notes no other note links to
+func (g *Graph) Orphans() []string {
+	linked := make(map[string]bool)
+	for _, e := range g.Edges {
+		if e.Source != e.Target {
+			linked[e.Target] = true
+		}
+	}
+	return g.nodesWithout(linked)
+}
+
+// Deadends returns the notes that do not link to any other note
+func (g *Graph) Deadends() []string {
+	linking := make(map[string]bool)
+	for _, e := range g.Edges {
+		if e.Source != e.Target {
+			linking[e.Source] = true
+		}
+	}
+	return g.nodesWithout(linking)
+}
+
+// HasNode reports whether a path from the vault root is a note of the graph
+func (g *Graph) HasNode(note string) bool {
+	for _, node := range g.Nodes {
+		if node == note {
+			return true
+		}
+	}
+	return false
+}
+
+// FindNodes returns the notes a name refers to: a path from the vault root, with or without the .md extension,
+// or otherwise the notes whose path ends with it, like a wikilink such as [[important]] or [[notes/important]]
+func (g *Graph) FindNodes(name string) []string {
+	name = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	if !strings.HasSuffix(name, ".md") {
+		name += ".md"
+	}
+	if g.HasNode(name) {
+		return []string{name}
+	}
+
+	nodes := make([]string, 0)
+	for _, node := range g.Nodes {
+		if strings.HasSuffix(node, "/"+name) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (g *Graph) nodesWithout(set map[string]bool) []string {
+	nodes := make([]string, 0)
+	for _, node := range g.Nodes {
+		if !set[node] {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,65 @@
+package olconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph_Reports(t *testing.T) {
+	dir := writeVault(t, map[string]string{
+		"index.md":    "[[a]] [B](sub/b.md)\n[[a#Setup|setup]]\n",
+		"a.md":        "[[index]] [[a#Self]] [[#Local]]\n",
+		"sub/b.md":    "![[img.png]] [[missing]]\n",
+		"lonely.md":   "[[lonely]]\n",
+		"img.png":     "",
+		"unlinked.md": "[[sub/b]]\n",
+	})
+
+	g, err := BuildGraph(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Edge{
+		{Source: "a.md", Target: "a.md", Kind: WikilinkKind, Text: "a > Self", Anchor: "Self", Line: 1},
+		{Source: "index.md", Target: "a.md", Kind: WikilinkKind, Text: "a", Line: 1},
+		{Source: "index.md", Target: "a.md", Kind: WikilinkKind, Text: "setup", Anchor: "Setup", Line: 2},
+	}, g.Backlinks("a.md"))
+	assert.Empty(t, g.Backlinks("unlinked.md"))
+
+	assert.Equal(t, []string{"lonely.md", "unlinked.md"}, g.Orphans())
+	assert.Equal(t, []string{"lonely.md", "sub/b.md"}, g.Deadends())
+
+	assert.True(t, g.HasNode("sub/b.md"))
+	assert.False(t, g.HasNode("img.png"))
+}
+
+func TestGraph_FindNodes(t *testing.T) {
+	dir := writeVault(t, map[string]string{
+		"a.md":       "",
+		"sub/a.md":   "",
+		"sub/b.md":   "",
+		"other/b.md": "",
+		"c.md":       "",
+	})
+
+	g, err := BuildGraph(dir)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"sub/b.md", []string{"sub/b.md"}},
+		{"./sub/b", []string{"sub/b.md"}},
+		{"a", []string{"a.md"}},
+		{"c", []string{"c.md"}},
+		{"b", []string{"other/b.md", "sub/b.md"}},
+		{"missing", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, g.FindNodes(tt.name))
+		})
+	}
+}
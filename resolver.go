@@ -0,0 +1,114 @@
+package olconv
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Resolver finds the files of a vault links point to.
+// Files are identified by their path, as listed by ListVaultFiles.
+type Resolver interface {
+	// Resolve returns the files a wikilink target written in the note at source may point to.
+	// The target has no heading fragment and no .md extension, e.g. sub/note or image.png.
+	// It returns no file when the target cannot be found, and several files when it is ambiguous.
+	Resolve(source, target string) []string
+	// WikilinkName returns the shortest target a wikilink to file can be written with,
+	// e.g. note, or sub/note when another note has the same name.
+	WikilinkName(file string) string
+}
+
+// FileMapResolver resolves links against the files of a vault grouped by name, as returned by FileListToMap.
+// It is the Resolver used by NewConverter.
+type FileMapResolver struct {
+	basepath string
+	filemap  map[string][]string
+}
+
+// NewFileMapResolver returns a Resolver for the vault at basepath with the given filemap
+func NewFileMapResolver(basepath string, filemap map[string][]string) *FileMapResolver {
+	return &FileMapResolver{
+		basepath: basepath,
+		filemap:  filemap,
+	}
+}
+
+// Resolve returns every file with the name of a bare target.
+// Targets containing a path are looked up from the vault root first, then from the directory of the source note,
+// and finally matched against the end of the paths of the files with the same name.
+func (r *FileMapResolver) Resolve(source, target string) []string {
+	target = strings.TrimSuffix(target, ".md")
+	files := r.filemap[extractFilename(target)]
+	if !strings.Contains(target, "/") {
+		return files
+	}
+
+	path := filepath.FromSlash(target)
+	for _, dir := range []string{r.basepath, filepath.Dir(source)} {
+		candidate := filepath.Join(dir, path)
+		for _, file := range files {
+			if clean := filepath.Clean(file); clean == candidate || clean == candidate+".md" {
+				return []string{file}
+			}
+		}
+	}
+
+	// shortest paths only keep the end of the path, e.g. b/samename for a/b/samename
+	matches := make([]string, 0)
+	for _, file := range files {
+		name := "/" + trimNoteExtension(vaultRelPath(r.basepath, file))
+		if strings.HasSuffix(name, "/"+target) {
+			matches = append(matches, file)
+		}
+	}
+	return matches
+}
+
+// WikilinkName returns the shortest trailing part of the path of file that no other file in the vault ends with,
+// e.g. b/samename for a/b/samename when c/samename also exists. Files missing from the filemap get their full path.
+func (r *FileMapResolver) WikilinkName(file string) string {
+	name := trimNoteExtension(vaultRelPath(r.basepath, file))
+	files := r.filemap[filenameWithoutMdExtension(file)]
+
+	others := make([]string, 0, len(files))
+	for _, other := range files {
+		if other := trimNoteExtension(vaultRelPath(r.basepath, other)); other != name {
+			others = append(others, other)
+		}
+	}
+	if len(others) == len(files) {
+		return name
+	}
+
+	parts := strings.Split(name, "/")
+	for k := 1; k < len(parts); k++ {
+		suffix := strings.Join(parts[len(parts)-k:], "/")
+		unique := true
+		for _, other := range others {
+			if other == suffix || strings.HasSuffix(other, "/"+suffix) {
+				unique = false
+				break
+			}
+		}
+		if unique {
+			return suffix
+		}
+	}
+	return name
+}
+
+// vaultRelPath returns the path of file from the vault root
+func vaultRelPath(basepath, file string) string {
+	rel, err := filepath.Rel(basepath, file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// trimNoteExtension removes the .md extension of a note, keeping the extension of any other file
+func trimNoteExtension(path string) string {
+	if isAttachment(path) {
+		return path
+	}
+	return strings.TrimSuffix(path, ".md")
+}
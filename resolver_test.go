@@ -0,0 +1,83 @@
+package olconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMapResolver(t *testing.T) {
+	r := NewFileMapResolver("vault", FileListToMap([]string{
+		"vault/index.md",
+		"vault/a/b/samename.md",
+		"vault/c/samename.md",
+		"vault/a/d/samename.md",
+		"vault/attachments/diagram.png",
+		"vault/a+b.md",
+		"vault/100%.md",
+	}))
+
+	tests := []struct {
+		name   string
+		source string
+		target string
+		want   []string
+	}{
+		{name: "bare name", source: "vault/index.md", target: "index", want: []string{"vault/index.md"}},
+		{name: "with extension", source: "vault/index.md", target: "index.md", want: []string{"vault/index.md"}},
+		{name: "ambiguous", source: "vault/index.md", target: "samename", want: []string{"vault/a/b/samename.md", "vault/c/samename.md", "vault/a/d/samename.md"}},
+		{name: "from the vault root", source: "vault/a/d/samename.md", target: "c/samename", want: []string{"vault/c/samename.md"}},
+		{name: "from the note", source: "vault/a/index.md", target: "d/samename", want: []string{"vault/a/d/samename.md"}},
+		{name: "end of the path", source: "vault/index.md", target: "b/samename", want: []string{"vault/a/b/samename.md"}},
+		{name: "attachment", source: "vault/index.md", target: "diagram.png", want: []string{"vault/attachments/diagram.png"}},
+		{name: "plus", source: "vault/index.md", target: "a+b", want: []string{"vault/a+b.md"}},
+		{name: "percent", source: "vault/index.md", target: "100%", want: []string{"vault/100%.md"}},
+		{name: "missing", source: "vault/index.md", target: "e/samename", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.Resolve(tt.source, tt.target))
+		})
+	}
+
+	assert.Equal(t, "index", r.WikilinkName("vault/index.md"))
+	assert.Equal(t, "b/samename", r.WikilinkName("vault/a/b/samename.md"))
+	assert.Equal(t, "c/samename", r.WikilinkName("vault/c/samename.md"))
+	assert.Equal(t, "diagram.png", r.WikilinkName("vault/attachments/diagram.png"))
+	assert.Equal(t, "e/samename", r.WikilinkName("vault/e/samename.md"))
+}
+
+func TestFileListToMap(t *testing.T) {
+	filemap := FileListToMap([]string{"vault/a+b.md", "vault/100%.md", "vault/read.md.md", "vault/diagram.png"})
+	assert.Equal(t, map[string][]string{
+		"a+b":         {"vault/a+b.md"},
+		"100%":        {"vault/100%.md"},
+		"read.md":     {"vault/read.md.md"},
+		"diagram.png": {"vault/diagram.png"},
+	}, filemap)
+}
+
+// aliasResolver resolves targets by note aliases before falling back to another Resolver
+type aliasResolver struct {
+	Resolver
+	aliases map[string]string
+}
+
+func (r aliasResolver) Resolve(source, target string) []string {
+	if file, ok := r.aliases[target]; ok {
+		return []string{file}
+	}
+	return r.Resolver.Resolve(source, target)
+}
+
+func TestConverter_customResolver(t *testing.T) {
+	c := NewConverter("vault", FileListToMap([]string{"vault/notes/Meeting notes.md", "vault/index.md"}))
+	c.Resolver = aliasResolver{
+		Resolver: c.Resolver,
+		aliases:  map[string]string{"Minutes": "vault/notes/Meeting notes.md"},
+	}
+	c.notePath = "vault/index.md"
+
+	assert.Equal(t, "[Minutes](notes/Meeting%20notes.md) [index](index.md)", c.convertLine("[[Minutes]] [[index]]", ToMarkdown))
+	assert.Equal(t, "[[Meeting notes|Minutes]]", c.convertLine("[Minutes](notes/Meeting%20notes.md)", ToWikilink))
+}
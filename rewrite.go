@@ -0,0 +1,88 @@
+package olconv
+
+// RewriteFunc decides the replacement of a link visited by a Converter.
+// It returns the text written in place of the link, as Markdown, and whether the link is changed.
+type RewriteFunc func(ctx LinkContext) (string, bool)
+
+// LinkContext describes a link visited by a RewriteFunc
+type LinkContext struct {
+	// Link is the link as parsed, positioned in the note
+	Link Link
+	// Note is the path of the note containing the link
+	Note string
+	// File is the file of the vault the link points to.
+	// It is empty for external links, links within the note and links that cannot be found.
+	File string
+	// Direction is the conversion applied to the link when it is not changed
+	Direction LinkDirection
+}
+
+// rewriteLine passes every link of a line to the Rewrite hook, and converts the links it leaves unchanged
+func (c *Converter) rewriteLine(line string, direction LinkDirection) string {
+	links := lineLinks(line, c.carry)
+
+	// start from last index to avoid index misalignment due to re-slicing
+	limit := len(line)
+	for i := len(links) - 1; i >= 0; i-- {
+		link := links[i]
+		if link.End > limit {
+			// nested in the link replaced before
+			continue
+		}
+		limit = link.Offset
+
+		start, end := link.Offset, link.End
+		link.Line = c.line
+		link.Column = start + 1
+		link.Offset += c.offset
+		link.End += c.offset
+		file, _ := c.linkedFile(link)
+
+		replacement, changed := c.Rewrite(LinkContext{
+			Link:      link,
+			Note:      c.notePath,
+			File:      file,
+			Direction: direction,
+		})
+		if changed {
+			c.converted++
+		} else {
+			replacement = c.convertLink(link, direction)
+		}
+		line = line[:start] + replacement + line[end:]
+	}
+	return line
+}
+
+// convertLink converts a single link in the given direction
+func (c *Converter) convertLink(link Link, direction LinkDirection) string {
+	switch {
+	case direction == ToWikilink && link.Kind == MarkdownLinkKind:
+		return c.convertMdToWikilink(link.Raw)
+	case direction == ToMarkdown && link.Kind == WikilinkKind:
+		return c.convertWikilinkToMd(link.Raw)
+	default:
+		return link.Raw
+	}
+}
+
+// linkedFile returns the file of the vault a link of the note being converted points to.
+// It returns false for external links, links within the note and links that cannot be found.
+func (c *Converter) linkedFile(link Link) (string, bool) {
+	if link.Target == "" {
+		return "", false
+	}
+
+	if link.Kind == MarkdownLinkKind {
+		if urlSchemePattern.MatchString(link.Target) {
+			return "", false
+		}
+		file, _, ok := c.findDestination(link.Target)
+		return file, ok
+	}
+
+	if len(c.matchWikilink(link.Target)) == 0 {
+		return "", false
+	}
+	return c.resolveWikilink(link.Target), true
+}
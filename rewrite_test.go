@@ -0,0 +1,81 @@
+package olconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_Rewrite(t *testing.T) {
+	c := NewConverter("vault", FileListToMap([]string{
+		"vault/index.md",
+		"vault/archive/old.md",
+		"vault/current.md",
+	}))
+
+	visited := make([]LinkContext, 0)
+	// move links to archived notes under archive/
+	c.Rewrite = func(ctx LinkContext) (string, bool) {
+		visited = append(visited, ctx)
+		if !strings.HasPrefix(ctx.File, "vault/archive/") || ctx.Link.Kind != WikilinkKind || strings.HasPrefix(ctx.Link.Target, "archive/") {
+			return "", false
+		}
+		return strings.Replace(ctx.Link.Raw, "[[", "[[archive/", 1), true
+	}
+
+	note := "---\n" +
+		"up: \"[[old]]\"\n" +
+		"---\n" +
+		"[[old|Old]] and [[current]] and [Current](current.md)\n" +
+		"```\n[[old]]\n```\n" +
+		"![[old#Heading]] [[#Local]]\n"
+
+	w := &bytes.Buffer{}
+	converted, err := c.convert(strings.NewReader(note), w, "vault/index.md", RewriteOnly)
+	require.NoError(t, err)
+	assert.Equal(t, "---\n"+
+		"up: \"[[old]]\"\n"+
+		"---\n"+
+		"[[archive/old|Old]] and [[current]] and [Current](current.md)\n"+
+		"```\n[[old]]\n```\n"+
+		"![[archive/old#Heading]] [[#Local]]\n", w.String())
+	assert.Equal(t, 2, converted)
+
+	// links are visited from the end of each line
+	require.Len(t, visited, 5)
+	assert.Equal(t, LinkContext{
+		Link: Link{
+			Kind:   MarkdownLinkKind,
+			Target: "current.md",
+			Text:   "Current",
+			Raw:    "[Current](current.md)",
+			Offset: 54,
+			End:    75,
+			Line:   4,
+			Column: 33,
+		},
+		Note:      "vault/index.md",
+		File:      "vault/current.md",
+		Direction: RewriteOnly,
+	}, visited[0])
+	assert.Equal(t, "vault/archive/old.md", visited[2].File)
+	assert.Equal(t, "", visited[3].File)
+	assert.Equal(t, "Local", visited[3].Link.Fragment)
+	assert.Equal(t, note[visited[4].Link.Offset:visited[4].Link.End], "![[old#Heading]]")
+}
+
+func TestConverter_RewriteWithConversion(t *testing.T) {
+	c := NewConverter("vault", FileListToMap([]string{"vault/index.md", "vault/note.md"}))
+	c.Rewrite = func(ctx LinkContext) (string, bool) {
+		if ctx.Link.Target != "legacy" {
+			return "", false
+		}
+		return "[Legacy](https://wiki.example.com/legacy)", true
+	}
+	c.notePath = "vault/index.md"
+
+	assert.Equal(t, "[note](note.md) [Legacy](https://wiki.example.com/legacy) [x](note.md)", c.convertLine("[[note]] [[legacy]] [x](note.md)", ToMarkdown))
+}
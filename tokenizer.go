@@ -0,0 +1,365 @@
+package olconv
+
+import (
+	"regexp"
+	"strings"
+)
+
+// blockKind is the kind of CommonMark block a line belongs to
+type blockKind int
+
+const (
+	// textBlock is any block whose inline content may contain links
+	textBlock blockKind = iota
+	fencedCodeBlock
+	indentedCodeBlock
+	htmlBlock
+)
+
+// blockTokenizer classifies the lines of a document into CommonMark blocks.
+// Only the blocks whose content must be left untouched are tracked precisely:
+// fenced and indented code blocks and HTML blocks.
+type blockTokenizer struct {
+	kind blockKind
+
+	// open fence
+	fenceChar byte
+	fenceLen  int
+
+	// end condition of the open HTML block
+	htmlEnd string
+
+	// indentation of the content of the open indented code block
+	codeIndent int
+
+	// content indentation of the open list items, innermost last
+	items []int
+
+	inParagraph bool
+	prevBlank   bool
+	// continued is set when the last line went on with the paragraph of the line before
+	continued bool
+}
+
+// next returns the kind of block the line belongs to and advances the tokenizer
+func (t *blockTokenizer) next(line string) blockKind {
+	blank := strings.TrimSpace(line) == ""
+	defer func() { t.prevBlank = blank }()
+	wasInParagraph := t.inParagraph
+	t.continued = false
+
+	switch t.kind {
+	case fencedCodeBlock:
+		if t.isClosingFence(line) {
+			t.kind = textBlock
+		}
+		return fencedCodeBlock
+	case htmlBlock:
+		if t.htmlEnd == "" {
+			// HTML blocks started by a tag end at a blank line, which is not part of the block
+			if blank {
+				t.kind = textBlock
+				t.inParagraph = false
+				return textBlock
+			}
+			return htmlBlock
+		}
+		if strings.Contains(strings.ToLower(line), t.htmlEnd) {
+			t.kind = textBlock
+		}
+		return htmlBlock
+	case indentedCodeBlock:
+		if blank || indentWidth(line) >= t.codeIndent {
+			return indentedCodeBlock
+		}
+		t.kind = textBlock
+	}
+
+	if blank {
+		t.inParagraph = false
+		return textBlock
+	}
+
+	indent := indentWidth(line)
+	trimmed := trimBlockquote(line)
+	marker := listItemPattern.FindStringSubmatch(trimmed)
+
+	// a line indented less than the content of a list item closes it, unless it is the lazy continuation of a paragraph
+	for len(t.items) > 0 && indent < t.items[len(t.items)-1] && (t.prevBlank || !t.inParagraph || marker != nil) {
+		t.items = t.items[:len(t.items)-1]
+	}
+	base := 0
+	if len(t.items) > 0 {
+		base = t.items[len(t.items)-1]
+	}
+
+	if indent-base >= 4 && !t.inParagraph {
+		t.kind = indentedCodeBlock
+		t.codeIndent = base + 4
+		return indentedCodeBlock
+	}
+
+	if marker != nil {
+		// the content of a list item starts after the marker and up to 4 spaces, and can start a block of its own
+		rest := trimmed[len(marker[1]):]
+		spaces := indentWidth(rest)
+		if strings.TrimSpace(rest) == "" || spaces > 4 {
+			spaces = 1
+		}
+		t.items = append(t.items, indent+len(marker[1])+spaces)
+		trimmed = strings.TrimLeft(rest, " \t")
+	}
+
+	if indent-base < 4 {
+		if char, length, ok := openingFence(trimmed); ok {
+			t.kind = fencedCodeBlock
+			t.fenceChar = char
+			t.fenceLen = length
+			t.inParagraph = false
+			return fencedCodeBlock
+		}
+		if end, ok := htmlBlockStart(trimmed, t.inParagraph); ok {
+			t.kind = htmlBlock
+			t.htmlEnd = end
+			// the end condition may be met on the opening line itself
+			if end != "" && strings.Contains(strings.ToLower(trimmed[1:]), end) {
+				t.kind = textBlock
+			}
+			return htmlBlock
+		}
+	}
+
+	heading := strings.HasPrefix(trimmed, "#")
+	t.continued = wasInParagraph && marker == nil && !heading
+	t.inParagraph = !heading
+	return textBlock
+}
+
+func (t *blockTokenizer) isClosingFence(line string) bool {
+	trimmed := strings.TrimRight(trimBlockquote(line), " \t")
+	if len(trimmed) < t.fenceLen {
+		return false
+	}
+	return strings.Trim(trimmed, string(t.fenceChar)) == ""
+}
+
+var listItemPattern = regexp.MustCompile(`^([-+*]|\d{1,9}[.)])(?:[ \t]|$)`)
+
+// trimBlockquote removes the indentation and blockquote markers at the start of a line
+func trimBlockquote(line string) string {
+	line = strings.TrimLeft(line, " \t")
+	for strings.HasPrefix(line, ">") {
+		line = strings.TrimLeft(line[1:], " \t")
+	}
+	return line
+}
+
+// indentWidth returns the indentation of a line in columns, expanding tabs to multiples of 4
+func indentWidth(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// openingFence reports whether the line opens a fenced code block with ``` or ~~~
+func openingFence(line string) (byte, int, bool) {
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return 0, 0, false
+	}
+	char := line[0]
+	length := len(line) - len(strings.TrimLeft(line, string(char)))
+	if length < 3 {
+		return 0, 0, false
+	}
+	// the info string of a backtick fence cannot contain backticks
+	if char == '`' && strings.Contains(line[length:], "`") {
+		return 0, 0, false
+	}
+	return char, length, true
+}
+
+var (
+	htmlRawTagPattern      = regexp.MustCompile(`(?i)^<(script|pre|style|textarea)([ \t>]|$)`)
+	htmlBlockTagPattern    = regexp.MustCompile(`(?i)^</?(address|article|aside|base|basefont|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|figure|footer|form|frame|frameset|h[1-6]|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|nav|noframes|ol|optgroup|option|p|param|search|section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul)([ \t>]|/>|$)`)
+	htmlCompleteTagPattern = regexp.MustCompile(`^(<[A-Za-z][A-Za-z0-9-]*(\s+[A-Za-z_:][A-Za-z0-9_.:-]*(\s*=\s*("[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?)*\s*/?>|</[A-Za-z][A-Za-z0-9-]*\s*>)\s*$`)
+)
+
+// htmlBlockStart reports whether the line starts a CommonMark HTML block.
+// It returns the lowercase string that ends the block, or an empty string when the block ends at a blank line.
+func htmlBlockStart(line string, inParagraph bool) (string, bool) {
+	if !strings.HasPrefix(line, "<") {
+		return "", false
+	}
+
+	switch {
+	case htmlRawTagPattern.MatchString(line):
+		tag := strings.ToLower(htmlRawTagPattern.FindStringSubmatch(line)[1])
+		return "</" + tag + ">", true
+	case strings.HasPrefix(line, "<!--"):
+		return "-->", true
+	case strings.HasPrefix(line, "<?"):
+		return "?>", true
+	case strings.HasPrefix(line, "<![CDATA["):
+		return "]]>", true
+	case len(line) > 2 && line[1] == '!' && isASCIILetter(line[2]):
+		return ">", true
+	case htmlBlockTagPattern.MatchString(line):
+		return "", true
+	case !inParagraph && htmlCompleteTagPattern.MatchString(line):
+		return "", true
+	}
+	return "", false
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// codeSpans holds the byte ranges [start, end) of the code spans in a line
+type codeSpans [][2]int
+
+// spanCarry describes the code spans that go on over the line breaks of a paragraph, by the length of their backtick run:
+// in for the span open at the start of a line, and out for the span left open at its end. Zero means there is none.
+type spanCarry struct {
+	in  int
+	out int
+}
+
+// lineToken is the block a line belongs to, and the code spans it shares with the other lines of its paragraph
+type lineToken struct {
+	kind  blockKind
+	carry spanCarry
+}
+
+// tokenize classifies the lines of a document.
+// Code spans are looked for in whole paragraphs, since a code span can go on over several lines.
+func tokenize(lines []string) []lineToken {
+	tokens := make([]lineToken, len(lines))
+	blocks := blockTokenizer{}
+	start := 0
+	for i, line := range lines {
+		tokens[i].kind = blocks.next(line)
+		if !blocks.continued {
+			carryCodeSpans(lines[start:i], tokens[start:i])
+			start = i
+		}
+	}
+	carryCodeSpans(lines[start:], tokens[start:])
+	return tokens
+}
+
+// carryCodeSpans records the code spans crossing the line breaks of a paragraph in the tokens of its lines
+func carryCodeSpans(lines []string, tokens []lineToken) {
+	if len(lines) < 2 || tokens[0].kind != textBlock {
+		return
+	}
+
+	starts := make([]int, len(lines))
+	for i := 1; i < len(lines); i++ {
+		starts[i] = starts[i-1] + len(lines[i-1]) + 1
+	}
+	lineAt := func(pos int) int {
+		i := 0
+		for i+1 < len(starts) && starts[i+1] <= pos {
+			i++
+		}
+		return i
+	}
+
+	text := strings.Join(lines, "\n")
+	for _, span := range findCodeSpans(text, spanCarry{}) {
+		first, last := lineAt(span[0]), lineAt(span[1]-1)
+		if first == last {
+			continue
+		}
+		length := len(text[span[0]:]) - len(strings.TrimLeft(text[span[0]:], "`"))
+		tokens[first].carry.out = length
+		for i := first + 1; i <= last; i++ {
+			tokens[i].carry.in = length
+			if i < last {
+				tokens[i].carry.out = length
+			}
+		}
+	}
+}
+
+// findCodeSpans finds the code spans in a line.
+// A code span opens with a run of backticks and is closed by the next run of the same length.
+// carry tells the spans going on from the line before and to the line after, see tokenize.
+func findCodeSpans(line string, carry spanCarry) codeSpans {
+	spans := codeSpans{}
+	i := 0
+	if carry.in > 0 {
+		end := closingRun(line, 0, carry.in)
+		if end == -1 {
+			return codeSpans{{0, len(line)}}
+		}
+		spans = append(spans, [2]int{0, end})
+		i = end
+	}
+
+	for i < len(line) {
+		if line[i] == '\\' {
+			// an escaped backtick cannot open a code span
+			i += 2
+			continue
+		}
+		if line[i] != '`' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] == '`' {
+			i++
+		}
+		length := i - start
+
+		if end := closingRun(line, i, length); end != -1 {
+			spans = append(spans, [2]int{start, end})
+			i = end
+		} else if length == carry.out {
+			// closed on a following line
+			spans = append(spans, [2]int{start, len(line)})
+			break
+		}
+	}
+	return spans
+}
+
+// closingRun returns the end of the first run of exactly length backticks from position i, or -1 when there is none
+func closingRun(line string, i, length int) int {
+	for i < len(line) {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(line) && line[i] == '`' {
+			i++
+		}
+		if i-start == length {
+			return i
+		}
+	}
+	return -1
+}
+
+// contains reports whether the byte at position i is part of a code span
+func (s codeSpans) contains(i int) bool {
+	for _, span := range s {
+		if span[0] <= i && i < span[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package olconv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockTokenizer(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []blockKind
+	}{
+		{
+			name:  "backtick fence with info string",
+			input: "text\n```go\n[x](x.md)\n```\ntext",
+			want:  []blockKind{textBlock, fencedCodeBlock, fencedCodeBlock, fencedCodeBlock, textBlock},
+		},
+		{
+			name:  "tilde fence is not closed by backticks",
+			input: "~~~\n```\n[x](x.md)\n~~~\ntext",
+			want:  []blockKind{fencedCodeBlock, fencedCodeBlock, fencedCodeBlock, fencedCodeBlock, textBlock},
+		},
+		{
+			name:  "four backtick fence containing three backticks",
+			input: "````md\n```\n[x](x.md)\n```\n````\ntext",
+			want:  []blockKind{fencedCodeBlock, fencedCodeBlock, fencedCodeBlock, fencedCodeBlock, fencedCodeBlock, textBlock},
+		},
+		{
+			name:  "backticks with a backtick in the info string are not a fence",
+			input: "``` `x` ```\ntext",
+			want:  []blockKind{textBlock, textBlock},
+		},
+		{
+			name:  "indented code block",
+			input: "text\n\n    [x](x.md)\n\n    more code\ntext",
+			want:  []blockKind{textBlock, textBlock, indentedCodeBlock, indentedCodeBlock, indentedCodeBlock, textBlock},
+		},
+		{
+			name:  "indented paragraph continuation is not code",
+			input: "text\n    [x](x.md)",
+			want:  []blockKind{textBlock, textBlock},
+		},
+		{
+			name:  "nested list items are not code",
+			input: "- item\n    - [x](x.md)\n\n\t- [y](y.md)",
+			want:  []blockKind{textBlock, textBlock, textBlock, textBlock},
+		},
+		{
+			name:  "fence in a list item",
+			input: "- item\n    ```\n    [x](x.md)\n    ```\n- [y](y.md)",
+			want:  []blockKind{textBlock, fencedCodeBlock, fencedCodeBlock, fencedCodeBlock, textBlock},
+		},
+		{
+			name:  "fence in a blockquote",
+			input: "> ```\n> [x](x.md)\n> ```\n> [y](y.md)",
+			want:  []blockKind{fencedCodeBlock, fencedCodeBlock, fencedCodeBlock, textBlock},
+		},
+		{
+			name:  "indented code in a list item",
+			input: "- a\n\n      [x](x.md)\n\n  [y](y.md)",
+			want:  []blockKind{textBlock, textBlock, indentedCodeBlock, indentedCodeBlock, textBlock},
+		},
+		{
+			name:  "indented code in an ordered list item",
+			input: "10. a\n\n        [x](x.md)\n    [y](y.md)",
+			want:  []blockKind{textBlock, textBlock, indentedCodeBlock, textBlock},
+		},
+		{
+			name:  "indented code after a list",
+			input: "- a\n\ntext\n\n    [x](x.md)",
+			want:  []blockKind{textBlock, textBlock, textBlock, textBlock, indentedCodeBlock},
+		},
+		{
+			name:  "lazy continuation stays in the list item",
+			input: "- a\nb\n\n      [x](x.md)",
+			want:  []blockKind{textBlock, textBlock, textBlock, indentedCodeBlock},
+		},
+		{
+			name:  "html block ends at a blank line",
+			input: "<div>\n[x](x.md)\n\n[y](y.md)",
+			want:  []blockKind{htmlBlock, htmlBlock, textBlock, textBlock},
+		},
+		{
+			name:  "html comment ends at the closing marker",
+			input: "<!--\n[x](x.md)\n-->\n[y](y.md)\n<!-- one line -->\n[z](z.md)",
+			want:  []blockKind{htmlBlock, htmlBlock, htmlBlock, textBlock, htmlBlock, textBlock},
+		},
+		{
+			name:  "inline html in a paragraph",
+			input: "text\n<span>\n[x](x.md)",
+			want:  []blockKind{textBlock, textBlock, textBlock},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer := blockTokenizer{}
+			got := make([]blockKind, 0)
+			for _, line := range strings.Split(tt.input, "\n") {
+				got = append(got, tokenizer.next(line))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFindCodeSpans(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		carry spanCarry
+		want  codeSpans
+	}{
+		{
+			name:  "single backtick",
+			input: "a `[x](x.md)` b",
+			want:  codeSpans{{2, 13}},
+		},
+		{
+			name:  "double backticks containing a backtick",
+			input: "``a ` [x](x.md)`` [y](y.md)",
+			want:  codeSpans{{0, 17}},
+		},
+		{
+			name:  "unmatched backtick is literal",
+			input: "a ` [x](x.md)",
+			want:  codeSpans{},
+		},
+		{
+			name:  "escaped backtick",
+			input: "\\` [x](x.md) `code`",
+			want:  codeSpans{{13, 19}},
+		},
+		{
+			name:  "span open from the line before",
+			input: "[x](x.md)` [y](y.md) ``a``",
+			carry: spanCarry{in: 1},
+			want:  codeSpans{{0, 10}, {21, 26}},
+		},
+		{
+			name:  "span open over the whole line",
+			input: "[x](x.md)",
+			carry: spanCarry{in: 2, out: 2},
+			want:  codeSpans{{0, 9}},
+		},
+		{
+			name:  "span left open to the line after",
+			input: "a ` [x](x.md) `` [y](y.md)",
+			carry: spanCarry{out: 2},
+			want:  codeSpans{{14, 26}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, findCodeSpans(tt.input, tt.carry))
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []spanCarry
+	}{
+		{
+			name:  "code span over two lines",
+			input: "text `code\n[[x]] more` end",
+			want:  []spanCarry{{out: 1}, {in: 1}},
+		},
+		{
+			name:  "code span over three lines",
+			input: "``a\n[[x]]\nb`` c",
+			want:  []spanCarry{{out: 2}, {in: 2, out: 2}, {in: 2}},
+		},
+		{
+			name:  "code spans end with the paragraph",
+			input: "text `code\n\n[[x]] more` end",
+			want:  []spanCarry{{}, {}, {}},
+		},
+		{
+			name:  "code spans do not go on into a list item",
+			input: "text `code\n- [[x]] more` end",
+			want:  []spanCarry{{}, {}},
+		},
+		{
+			name:  "code spans do not go on into a heading",
+			input: "text `code\n# [[x]] more` end",
+			want:  []spanCarry{{}, {}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]spanCarry, 0)
+			for _, token := range tokenize(strings.Split(tt.input, "\n")) {
+				got = append(got, token.carry)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
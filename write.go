@@ -0,0 +1,82 @@
+package olconv
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// MtimePolicy decides the modification time of rewritten notes
+type MtimePolicy int
+
+const (
+	// UpdateMtime lets the modification time reflect the rewrite
+	UpdateMtime MtimePolicy = iota
+	// PreserveMtime restores the modification time the note had before the rewrite
+	PreserveMtime
+)
+
+// writeFileAtomic replaces the content of an existing file through a temporary file in the same directory,
+// so the original is left untouched if writing fails. The file mode is kept as it was.
+// A symbolic link is kept, and the file it points to is replaced instead.
+func writeFileAtomic(path string, data []byte, mtime MtimePolicy) error {
+	staged, err := stageFile(path, data, mtime)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(staged.tmp, staged.path); err != nil {
+		os.Remove(staged.tmp)
+		return err
+	}
+	return nil
+}
+
+// stagedFile is the new content of a file written to a temporary file, which replaces the file when renamed to path
+type stagedFile struct {
+	tmp  string
+	path string
+}
+
+// stageFile writes the new content of the file at path to a temporary file in the same directory,
+// with the mode of the file and, with PreserveMtime, its modification time.
+// The path of a symbolic link is resolved, so that the file it points to is replaced.
+func stageFile(path string, data []byte, mtime MtimePolicy) (staged stagedFile, err error) {
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return staged, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return staged, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".olconv-*")
+	if err != nil {
+		return staged, err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return staged, err
+	}
+	if err = tmp.Sync(); err != nil {
+		return staged, err
+	}
+	if err = tmp.Chmod(info.Mode().Perm()); err != nil {
+		return staged, err
+	}
+	if err = tmp.Close(); err != nil {
+		return staged, err
+	}
+	if mtime == PreserveMtime {
+		if err = os.Chtimes(tmp.Name(), info.ModTime(), info.ModTime()); err != nil {
+			return staged, err
+		}
+	}
+
+	return stagedFile{tmp: tmp.Name(), path: path}, nil
+}
@@ -0,0 +1,86 @@
+package olconv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name        string
+		mtime       MtimePolicy
+		keepModTime bool
+	}{
+		{
+			name:        "update mtime",
+			mtime:       UpdateMtime,
+			keepModTime: false,
+		},
+		{
+			name:        "preserve mtime",
+			mtime:       PreserveMtime,
+			keepModTime: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			path := filepath.Join(tempDir, "note.md")
+			require.NoError(t, os.WriteFile(path, []byte("before"), 0600))
+			modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+			require.NoError(t, writeFileAtomic(path, []byte("after"), tt.mtime))
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "after", string(content))
+
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+			assert.Equal(t, tt.keepModTime, info.ModTime().Equal(modTime))
+
+			// no temporary file is left behind
+			entries, err := os.ReadDir(tempDir)
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
+
+func TestWriteFileAtomic_MissingFile(t *testing.T) {
+	err := writeFileAtomic(filepath.Join(t.TempDir(), "missing.md"), []byte("content"), UpdateMtime)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestWriteFileAtomic_Symlink(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, "shared", "note.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(target), 0755))
+	require.NoError(t, os.WriteFile(target, []byte("before"), 0644))
+	link := filepath.Join(tempDir, "vault", "note.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(link), 0755))
+	require.NoError(t, os.Symlink(filepath.Join("..", "shared", "note.md"), link))
+
+	require.NoError(t, writeFileAtomic(link, []byte("after"), UpdateMtime))
+
+	// the link is kept and the file it points to is updated
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+	content, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "after", string(content))
+
+	// the temporary file is created next to the target
+	entries, err := os.ReadDir(filepath.Dir(link))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}